@@ -57,6 +57,43 @@ CREATE TABLE IF NOT EXISTS Order_contents (
 
 );
 
+-- Журнал обработанных заказов: последняя записанная версия (date_created) каждого заказа
+CREATE TABLE IF NOT EXISTS Processed_orders (
+    order_uid VARCHAR(30) PRIMARY KEY,
+    version TIMESTAMP WITH TIME ZONE NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Расхождения сумм в заказах для проверки финансистами (по текущей версии заказа)
+CREATE TABLE IF NOT EXISTS Finance_mismatches (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR(30) NOT NULL,
+    check_name VARCHAR(30) NOT NULL,
+    path VARCHAR(100) NOT NULL,
+    currency VARCHAR(10),
+    expected DECIMAL(14, 4),
+    actual DECIMAL(14, 4),
+    tolerance DECIMAL(12, 4),
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS finance_mismatches_order_uid ON Finance_mismatches (order_uid);
+
+-- Текущий статус заказа из топиков order-status и order-cancel (заказ может прийти позже статуса)
+CREATE TABLE IF NOT EXISTS Order_status (
+    order_uid VARCHAR(30) PRIMARY KEY,
+    status VARCHAR(30) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Отмены заказов из топика order-cancel
+CREATE TABLE IF NOT EXISTS Order_cancellations (
+    order_uid VARCHAR(30) PRIMARY KEY,
+    reason TEXT,
+    cancelled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
 CREATE TABLE IF NOT EXISTS Hash (
     order_id VARCHAR(30) PRIMARY KEY REFERENCES Orders(order_uid)
 );
\ No newline at end of file
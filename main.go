@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,18 +13,38 @@ import (
 	cache "project_wb_l0/modules/cache"
 	"project_wb_l0/modules/config"
 	"project_wb_l0/modules/consumer"
+	"project_wb_l0/modules/general"
+	"project_wb_l0/modules/registry"
+	"project_wb_l0/modules/resp"
+	"project_wb_l0/modules/retry"
+	"project_wb_l0/modules/schema"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout — сколько HTTP сервер ждёт завершения запросов при остановке
+const shutdownTimeout = 10 * time.Second
+
 // getOrderByID — обработчик Gin для получения заказа по ID.
-// cache.Get(id) - сначала ищет в кэше и только при необходимости обращается к БД.
+// cache.Fetch(ctx, id) - сначала ищет в кэше и только при необходимости обращается к БД.
+// Заголовок X-Cache показывает, откуда взят заказ: HIT — из памяти, MISS — из БД
 func getOrderByID(c *gin.Context, cache *cache.Cache) {
 	id := c.Param("id")
 	log.Printf("Ищем заказ с UID: %s", id)
 
-	order, err := cache.Get(id)
+	order, hit, err := cache.Fetch(c.Request.Context(), id)
+	if hit {
+		c.Header("X-Cache", "HIT")
+	} else {
+		c.Header("X-Cache", "MISS")
+	}
+	if errors.Is(err, general.ErrOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Println(err)
 	}
@@ -30,6 +52,22 @@ func getOrderByID(c *gin.Context, cache *cache.Cache) {
 	c.JSON(http.StatusOK, order)
 }
 
+// getOrderStatus — обработчик Gin для получения текущего статуса заказа
+// из топиков смен статусов и отмен
+func getOrderStatus(c *gin.Context, db *database.Db) {
+	status, err := db.OrderStatus(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, general.ErrOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка чтения статуса заказа: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "внутренняя ошибка"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
 // RegisterWebRoutes — регистрирует маршруты веб-интерфейса.
 func RegisterWebRoutes(r *gin.Engine) {
 	r.LoadHTMLGlob("templates/*.html")
@@ -39,6 +77,129 @@ func RegisterWebRoutes(r *gin.Engine) {
 	})
 }
 
+// registerOrderSchemas регистрирует Avro- и Protobuf-схемы заказа,
+// чтобы продюсеры могли ссылаться на них по номеру
+func registerOrderSchemas(ctx context.Context, reg *registry.Client) {
+	schemas := map[string]registry.Schema{
+		"order-avro":     {Type: registry.TypeAvro, Schema: schema.OrderAvsc()},
+		"order-protobuf": {Type: registry.TypeProtobuf, Schema: schema.OrderProto()},
+	}
+	for subject, s := range schemas {
+		id, err := reg.Register(ctx, subject, s)
+		if err != nil {
+			log.Printf("Не удалось зарегистрировать схему %s: %v", subject, err)
+			continue
+		}
+		log.Printf("Схема %s зарегистрирована под номером %d", subject, id)
+	}
+}
+
+// RegisterSchemaRoutes — отдаёт JSON Schema заказа, по которой консюмер проверяет сообщения
+func RegisterSchemaRoutes(r *gin.Engine) {
+	serve := func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/schema+json", schema.OrderJSON())
+	}
+	r.GET("/schema/order", serve)
+	r.GET("/schema/order/"+schema.OrderVersion, serve)
+}
+
+// RegisterIngestRoutes — регистрирует админские маршруты записи заказов из Kafka
+func RegisterIngestRoutes(r *gin.Engine, db *database.Db, consumers ...*consumer.Consumer) {
+	admin := r.Group("/admin/ingest", adminAuth)
+
+	// Сколько сообщений каждого топика прочитано, отклонено, записано и не записано
+	admin.GET("/topics", func(c *gin.Context) {
+		var stats []consumer.TopicStats
+		for _, cons := range consumers {
+			stats = append(stats, cons.Stats()...)
+		}
+		c.JSON(http.StatusOK, gin.H{"topics": stats})
+	})
+
+	// Сколько заказов записано и сколько отброшено как повторы или устаревшие версии
+	admin.GET("/stats", func(c *gin.Context) {
+		c.JSON(http.StatusOK, db.IngestStats())
+	})
+
+	// Расхождения сумм в заказах для проверки финансистами (?limit=, по умолчанию 100)
+	admin.GET("/finance-mismatches", func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "неверный limit"})
+			return
+		}
+		mismatches, err := db.FinanceMismatches(c.Request.Context(), limit)
+		if err != nil {
+			log.Printf("Ошибка чтения расхождений сумм: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "внутренняя ошибка"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"count": len(mismatches), "mismatches": mismatches})
+	})
+}
+
+// adminAuth проверяет токен администратора.
+// Без ADMIN_TOKEN админские запросы не принимаются вовсе.
+// Токены сравниваются за постоянное время, чтобы по времени ответа нельзя было подобрать токен
+func adminAuth(c *gin.Context) {
+	token := c.GetHeader("X-Admin-Token")
+	if config.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "неверный токен администратора"})
+	}
+}
+
+// RegisterHealthRoutes — регистрирует проверку здоровья.
+// Отвечает 503, если хотя бы один консюмер не читает сообщения
+func RegisterHealthRoutes(r *gin.Engine, consumers ...*consumer.Consumer) {
+	r.GET("/health", func(c *gin.Context) {
+		status, code := "ok", http.StatusOK
+		states := make([]consumer.Health, 0, len(consumers))
+		for _, cons := range consumers {
+			h := cons.Health()
+			if h.State != consumer.StateRunning {
+				status, code = "degraded", http.StatusServiceUnavailable
+			}
+			states = append(states, h)
+		}
+		c.JSON(code, gin.H{"status": status, "consumers": states})
+	})
+}
+
+// RegisterAdminRoutes — регистрирует маршруты администрирования кэша.
+// Запросы должны передавать ADMIN_TOKEN в заголовке X-Admin-Token
+func RegisterAdminRoutes(r *gin.Engine, cache *cache.Cache) {
+	admin := r.Group("/admin/cache", adminAuth)
+
+	// Статистика попаданий, промахов, вытеснений и задержек загрузки
+	admin.GET("/stats", func(c *gin.Context) {
+		c.JSON(http.StatusOK, cache.Stats())
+	})
+
+	// Список UID'ов в кэше
+	admin.GET("/keys", func(c *gin.Context) {
+		keys := cache.Keys()
+		c.JSON(http.StatusOK, gin.H{"count": len(keys), "keys": keys})
+	})
+
+	// Удаление одного заказа из кэша и Hash
+	admin.DELETE("/:id", func(c *gin.Context) {
+		id := c.Param("id")
+		if !cache.Delete(id) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "заказа нет в кэше"})
+			return
+		}
+		log.Printf("Заказ %s удалён из кэша администратором", id)
+		c.JSON(http.StatusOK, gin.H{"deleted": id})
+	})
+
+	// Полная очистка кэша и Hash
+	admin.DELETE("", func(c *gin.Context) {
+		n := cache.Flush()
+		log.Printf("Кэш очищен администратором, удалено записей: %d", n)
+		c.JSON(http.StatusOK, gin.H{"deleted": n})
+	})
+}
+
 func main() {
 
 	// Насртойка для graceful shutdown
@@ -69,33 +230,153 @@ func main() {
 	}
 
 	// Инициализируем кэш
-	cache := cache.NewCache(config.CacheMaxItems, db)
+	cacheOpts := []cache.Option{
+		cache.WithPolicy(config.CachePolicy),
+		cache.WithShards(config.CacheShards),
+		cache.WithMaxBytes(config.CacheMaxBytes),
+		cache.WithTTL(config.CacheTTL),
+		cache.WithSlidingExpiration(config.CacheSlidingTTL),
+		cache.WithJanitorInterval(config.CacheJanitorInterval),
+		cache.WithNegativeCaching(config.CacheNegativeTTL, config.CacheNegativeMaxItems),
+		cache.WithWriteThrough(config.CacheWriteThrough),
+		cache.WithRestore(config.CacheRestoreWorkers, config.CacheRestoreBatch),
+	}
+	// Второй уровень кэша в Redis, общий для всех экземпляров сервиса
+	if config.RedisAddr != "" {
+		redis := resp.NewClient(config.RedisAddr, config.RedisPassword, config.RedisPoolSize, config.RedisTimeout)
+		defer redis.Close()
+		cacheOpts = append(cacheOpts,
+			cache.WithRemoteTier(cache.NewRedisTier(redis, config.RedisKeyPrefix), config.RedisTTL))
+		log.Printf("Второй уровень кэша: Redis %s", config.RedisAddr)
+	}
+	cache := cache.NewCache(config.CacheMaxItems, db, cacheOpts...)
+	cache.Start(ctx)
 	log.Println("Кэш настроен")
 
-	// Восстановление кэша их БД
-	if err := cache.RestoreFromDB(); err != nil {
-		log.Printf("Предупреждение: не удалось восстановить кэш из БД: %v", err)
-	} else {
-		log.Println("Кэш успешно восстановлен из БД")
+	// Восстановление кэша из снимка, а если его нет или он повреждён — из БД
+	restored := false
+	if config.CacheSnapshotPath != "" {
+		n, err := cache.LoadSnapshot(config.CacheSnapshotPath)
+		if err != nil {
+			log.Printf("Предупреждение: не удалось загрузить снимок кэша: %v", err)
+		} else {
+			log.Printf("Кэш восстановлен из снимка, записей: %d", n)
+			restored = true
+		}
+	}
+	if !restored {
+		summary, err := cache.RestoreFromDB(ctx)
+		if err != nil {
+			log.Printf("Предупреждение: не удалось восстановить кэш из БД: %v", err)
+		} else {
+			log.Printf("Кэш восстановлен из БД: %s", summary)
+		}
 	}
 
 	// Запуск консьюмера\ов для кафки и подключение их к бд
+	consumerOpts := []consumer.Option{consumer.WithWorkers(config.KafkaWorkers)}
+	var schemaRegistry *registry.Client
+	registryURL := config.SchemaRegistryURL
+	if config.SchemaRegistryEmbeddedAddr != "" {
+		srv, err := registry.NewServer(config.SchemaRegistryEmbeddedAddr)
+		if err != nil {
+			log.Fatalf("Не удалось запустить встроенный реестр схем: %v\n", err)
+		}
+		defer srv.Close()
+		log.Printf("Встроенный реестр схем запущен на %s", srv.URL())
+		if registryURL == "" {
+			registryURL = srv.URL()
+		}
+		registerOrderSchemas(ctx, registry.NewClient(srv.URL(), config.SchemaRegistryTimeout))
+	}
+	if registryURL != "" {
+		schemaRegistry = registry.NewClient(registryURL, config.SchemaRegistryTimeout)
+	}
+	decoders, err := consumer.NewDecoders(schemaRegistry)
+	if err != nil {
+		log.Fatalf("Не удалось настроить декодеры сообщений: %v\n", err)
+	}
+	consumerOpts = append(consumerOpts, consumer.WithDecoders(decoders))
+	if config.SchemaValidation {
+		orderSchema, err := schema.Order()
+		if err != nil {
+			log.Fatalf("Не удалось загрузить схему заказа: %v\n", err)
+		}
+		consumerOpts = append(consumerOpts, consumer.WithSchema(orderSchema, config.SchemaStrict))
+	}
+	if config.ValidationRulesPath != "" {
+		rules, err := consumer.LoadRules(config.ValidationRulesPath)
+		if err != nil {
+			log.Fatalf("Не удалось загрузить правила валидации: %v\n", err)
+		}
+		consumerOpts = append(consumerOpts, consumer.WithRules(rules))
+	}
+	if config.FinanceCheck {
+		fc := consumer.NewFinanceCheck(config.FinanceDefaultTolerance, config.FinanceTolerances)
+		consumerOpts = append(consumerOpts, consumer.WithFinanceCheck(fc))
+	}
+	if config.KafkaDLQTopic != "" {
+		dlq := consumer.NewDeadLetterWriter([]string{config.KafkaBroker}, config.KafkaDLQTopic)
+		consumerOpts = append(consumerOpts, consumer.WithDeadLetter(dlq))
+	}
+	if config.KafkaStatusTopic != "" {
+		consumerOpts = append(consumerOpts, consumer.WithTopic(config.KafkaStatusTopic, consumer.StatusHandler()))
+	}
+	if config.KafkaCancelTopic != "" {
+		consumerOpts = append(consumerOpts, consumer.WithTopic(config.KafkaCancelTopic, consumer.CancelHandler()))
+	}
+	haltOnRetry := config.DBRetryExhausted == "halt"
+	switch config.DBRetryExhausted {
+	case "halt", "deadletter":
+	default:
+		log.Printf("Неизвестное значение DB_RETRY_EXHAUSTED=%q, используем deadletter\n", config.DBRetryExhausted)
+	}
+	if !haltOnRetry && config.KafkaDLQTopic == "" {
+		// Без dead-letter топика коммит такой пачки потерял бы заказы
+		log.Println("DB_RETRY_EXHAUSTED=deadletter без KAFKA_DLQ_TOPIC: при исчерпании попыток чтение будет остановлено (halt)")
+		haltOnRetry = true
+	}
+	if haltOnRetry {
+		consumerOpts = append(consumerOpts, consumer.WithHaltOnRetriesExhausted())
+	}
 	c1 := consumer.InitConsumer(ctx,
 		[]string{config.KafkaBroker},
 		config.KafkaTopic,
 		config.KafkaGroupID,
-		int(config.KafkaFetchWait.Seconds()),
+		config.KafkaFetchWait,
+		config.KafkaBatchSize,
+		config.KafkaBatchTimeout,
+		consumerOpts...,
 	)
-	log.Println(int(config.KafkaFetchWait.Seconds()))
-	db.StartListeningFromKafkaToWrite(ctx, c1)
+	db.SetRetryPolicy(retry.Policy{
+		Attempts:  config.DBRetryAttempts,
+		BaseDelay: config.DBRetryBaseDelay,
+		MaxDelay:  config.DBRetryMaxDelay,
+	})
+	db.OnOrderWritten(cache.OrderWritten)
+	if config.CacheListenUpdates {
+		db.ListenOrderUpdates(ctx, cache)
+	}
+	db.StartListeningFromKafkaToWrite(ctx, c1.Workers()...)
 
 	// Настройка Gin HTTP сервера
 	router := gin.Default()
 	RegisterWebRoutes(router)
+	if config.AdminToken != "" {
+		RegisterAdminRoutes(router, cache)
+		RegisterIngestRoutes(router, db, c1)
+	} else {
+		log.Println("ADMIN_TOKEN не задан, маршруты /admin/* отключены")
+	}
+	RegisterSchemaRoutes(router)
+	RegisterHealthRoutes(router, c1)
 
 	router.GET("/order/:id", func(c *gin.Context) {
 		getOrderByID(c, cache)
 	})
+	router.GET("/order/:id/status", func(c *gin.Context) {
+		getOrderStatus(c, db)
+	})
 
 	srv := &http.Server{
 		Addr:    ":5000",
@@ -113,10 +394,23 @@ func main() {
 	// Завершение работы
 	<-ctx.Done()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Ошибка при завершении сервера: %v\n", err)
+	// ctx уже отменён, поэтому на завершение запросов даём отдельный срок.
+	// Ошибка не прерывает выход: снимок кэша всё равно нужно сохранить
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Ошибка при завершении сервера: %v\n", err)
+	} else {
+		log.Println("HTTP сервер остановлен")
+	}
+
+	if config.CacheSnapshotPath != "" {
+		if err := cache.SaveSnapshot(config.CacheSnapshotPath); err != nil {
+			log.Printf("Не удалось сохранить снимок кэша: %v", err)
+		} else {
+			log.Println("Снимок кэша сохранён")
+		}
 	}
-	log.Println("HTTP сервер остановлен")
 
 	fmt.Println("Работа завершена. Выходим из main()")
 }
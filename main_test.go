@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/config"
+	"project_wb_l0/modules/consumer"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer func(token string) { config.AdminToken = token }(config.AdminToken)
+
+	r := gin.New()
+	r.GET("/admin/ping", adminAuth, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name, token, header string
+		want                int
+	}{
+		{"без ADMIN_TOKEN", "", "", http.StatusUnauthorized},
+		{"без заголовка", "secret", "", http.StatusUnauthorized},
+		{"неверный токен", "secret", "wrong", http.StatusUnauthorized},
+		{"верный токен", "secret", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AdminToken = tt.token
+			req := httptest.NewRequest(http.MethodGet, "/admin/ping", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Admin-Token", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("код %d, ожидался %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// waitState ждёт, пока консюмер перейдёт в состояние state
+func waitState(t *testing.T, c *consumer.Consumer, state string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Health().State != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("состояние %q, ожидалось %q", c.Health().State, state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHealthRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := consumer.InitConsumer(ctx, []string{broker}, "order-info", "health-test", 0, 10, time.Second)
+	r := gin.New()
+	RegisterHealthRoutes(r, c)
+
+	check := func(want int, wantStatus string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var body struct {
+			Status    string            `json:"status"`
+			Consumers []consumer.Health `json:"consumers"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != want || body.Status != wantStatus || len(body.Consumers) != 1 {
+			t.Fatalf("код %d, тело %s; ожидался %d %s", w.Code, w.Body, want, wantStatus)
+		}
+	}
+
+	// Чтение запущено и ждёт брокера
+	waitState(t, c, consumer.StateRunning)
+	check(http.StatusOK, "ok")
+
+	cancel()
+	waitState(t, c, consumer.StateStopped)
+	check(http.StatusServiceUnavailable, "degraded")
+}
@@ -0,0 +1,302 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"project_wb_l0/modules/consumer"
+	"project_wb_l0/modules/general"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Postgres ограничивает количество параметров одного запроса
+const maxQueryParams = 65535
+
+// Запись пачки заказов в базу данных одной транзакцией.
+// Сначала по журналу обработанных заказов отсеиваются повторы и устаревшие версии,
+// затем каждая таблица заполняется одним многострочным INSERT ... ON CONFLICT.
+// Внутри пачки строки с одинаковым ключом схлопываются: побеждает последняя,
+// как если бы заказы записывались по очереди. Вместе с заказами сохраняются
+// найденные в них расхождения сумм.
+// Возвращает заказы, которые действительно были записаны
+func (d *Db) writeOrders2Bd(records []consumer.Record) ([]general.Order, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	// Откатываемся, если появилась ошибка
+	defer tx.Rollback()
+
+	// 0. Пропускаем заказы, которые уже записаны в той же или более новой версии
+	all := make([]general.Order, len(records))
+	for i, rec := range records {
+		all[i] = rec.Order
+	}
+	accepted, dropped, err := d.acceptNewVersions(tx, all)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки журнала заказов: %w", err)
+	}
+	if len(accepted) == 0 {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("ошибка завершения транзакции: %w", err)
+		}
+		d.ingest.add(0, dropped)
+		return nil, nil
+	}
+
+	deliveries := newRowSet()
+	payments := newRowSet()
+	items := newRowSet()
+	ords := newRowSet()
+	contents := newRowSet()
+	var mismatches [][]any
+	orders := make([]general.Order, 0, len(accepted))
+	uids := make([]string, 0, len(accepted))
+
+	for _, i := range accepted {
+		order := records[i].Order
+		for _, m := range records[i].Mismatches {
+			mismatches = append(mismatches, []any{
+				order.OrderUID, m.Check, m.Path, m.Currency, m.Expected, m.Actual, m.Tolerance,
+			})
+		}
+		orders = append(orders, order)
+		deliveries.add(order.Delivery.Name,
+			order.Delivery.Name,
+			order.Delivery.Phone,
+			order.Delivery.Zip,
+			order.Delivery.City,
+			order.Delivery.Address,
+			order.Delivery.Region,
+			order.Delivery.Email,
+		)
+		payments.add(order.Payment.Transaction,
+			order.Payment.Transaction,
+			order.Payment.RequestID,
+			order.Payment.Currency,
+			order.Payment.Provider,
+			order.Payment.Amount,
+			order.Payment.PaymentDT,
+			order.Payment.Bank,
+			order.Payment.DeliveryCost,
+			order.Payment.GoodsTotal,
+			order.Payment.CustomFee,
+		)
+		for _, item := range order.Items {
+			items.add(item.ChrtID,
+				item.ChrtID,
+				item.Price,
+				item.Rid,
+				item.Name,
+				item.Sale,
+				item.TotalPrice,
+				item.NmID,
+				item.Brand,
+				item.Status,
+			)
+			contents.add(order.OrderUID+"\x00"+item.ChrtID, order.OrderUID, item.ChrtID)
+		}
+		ords.add(order.OrderUID,
+			order.OrderUID,
+			order.Entry,
+			order.Delivery.Name,
+			order.Payment.Transaction,
+			order.TrackNumber,
+			order.Locale,
+			order.InternalSignature,
+			order.CustomerID,
+			order.DeliveryService,
+			order.Shardkey,
+			order.SmID,
+			order.DateCreated,
+			order.OofShard,
+		)
+		uids = append(uids, order.OrderUID)
+	}
+
+	// 1. Сохраняем Delivery
+	err = bulkInsert(tx, `INSERT INTO Delivery (name, phone, zip, city, address, region, email)`,
+		`ON CONFLICT (name) DO UPDATE SET
+			phone = EXCLUDED.phone,
+			zip = EXCLUDED.zip,
+			city = EXCLUDED.city,
+			address = EXCLUDED.address,
+			region = EXCLUDED.region,
+			email = EXCLUDED.email`, deliveries.rows)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения Delivery: %w", err)
+	}
+
+	// 2. Сохраняем Payment
+	err = bulkInsert(tx, `INSERT INTO Payment (
+			transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)`,
+		`ON CONFLICT (transaction) DO UPDATE SET
+			request_id = EXCLUDED.request_id,
+			currency = EXCLUDED.currency,
+			provider = EXCLUDED.provider,
+			amount = EXCLUDED.amount,
+			payment_dt = EXCLUDED.payment_dt,
+			bank = EXCLUDED.bank,
+			delivery_cost = EXCLUDED.delivery_cost,
+			goods_total = EXCLUDED.goods_total,
+			custom_fee = EXCLUDED.custom_fee`, payments.rows)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения Payment: %w", err)
+	}
+
+	// 3. Сохраняем Items
+	err = bulkInsert(tx, `INSERT INTO Items (
+			chrt_id, price, rid, name, sale, total_price, nm_id, brand, status)`,
+		`ON CONFLICT (chrt_id) DO UPDATE SET
+			price = EXCLUDED.price,
+			rid = EXCLUDED.rid,
+			name = EXCLUDED.name,
+			sale = EXCLUDED.sale,
+			total_price = EXCLUDED.total_price,
+			nm_id = EXCLUDED.nm_id,
+			brand = EXCLUDED.brand,
+			status = EXCLUDED.status`, items.rows)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения Item: %w", err)
+	}
+
+	// 4. Сохраняем сами Orders
+	err = bulkInsert(tx, `INSERT INTO Orders (
+			order_uid, entry, delivery_id, payment_id, track_number, locale, internal_signature,
+			customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)`,
+		`ON CONFLICT (order_uid) DO UPDATE SET
+			entry = EXCLUDED.entry,
+			delivery_id = EXCLUDED.delivery_id,
+			payment_id = EXCLUDED.payment_id,
+			track_number = EXCLUDED.track_number,
+			locale = EXCLUDED.locale,
+			internal_signature = EXCLUDED.internal_signature,
+			customer_id = EXCLUDED.customer_id,
+			delivery_service = EXCLUDED.delivery_service,
+			shardkey = EXCLUDED.shardkey,
+			sm_id = EXCLUDED.sm_id,
+			date_created = EXCLUDED.date_created,
+			oof_shard = EXCLUDED.oof_shard`, ords.rows)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения Order: %w", err)
+	}
+
+	// 5. Связываем Order и Items через Order_contents
+	err = bulkInsert(tx, `INSERT INTO Order_contents (order_uid, chrt_id)`,
+		`ON CONFLICT (order_uid, chrt_id) DO NOTHING`, contents.rows)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения Order_contents: %w", err)
+	}
+
+	// 6. Заменяем расхождения сумм прежних версий заказов на найденные в новых
+	_, err = tx.Exec(`DELETE FROM Finance_mismatches WHERE order_uid = ANY($1)`, pq.Array(uids))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка очистки Finance_mismatches: %w", err)
+	}
+	err = bulkInsert(tx, `INSERT INTO Finance_mismatches (
+			order_uid, check_name, path, currency, expected, actual, tolerance)`, ``, mismatches)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения Finance_mismatches: %w", err)
+	}
+
+	// 7. Сообщаем другим экземплярам сервиса об изменении заказов (уйдёт после коммита)
+	err = d.notifyOrdersUpdated(tx, uids)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка отправки уведомления: %w", err)
+	}
+
+	// Завершаем транзакцию
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка завершения транзакции: %w", err)
+	}
+
+	d.ingest.add(len(orders), dropped)
+	log.Printf("Пачка из %d заказов успешно записана в БД", len(orders))
+	return orders, nil
+}
+
+// rowSet — строки для многострочного INSERT без повторов ключа.
+// Повторный ключ заменяет значения, сохраняя позицию первой строки
+type rowSet struct {
+	index map[string]int
+	rows  [][]any
+}
+
+func newRowSet() *rowSet {
+	return &rowSet{index: make(map[string]int)}
+}
+
+func (s *rowSet) add(key string, values ...any) {
+	if i, ok := s.index[key]; ok {
+		s.rows[i] = values
+		return
+	}
+	s.index[key] = len(s.rows)
+	s.rows = append(s.rows, values)
+}
+
+// bulkInsert выполняет "insert VALUES (...), (...) suffix" для всех строк,
+// разбивая их на запросы так, чтобы не превысить лимит параметров
+func bulkInsert(tx *sql.Tx, insert, suffix string, rows [][]any) error {
+	return bulkQuery(tx, insert, suffix, rows, nil)
+}
+
+// bulkQuery — bulkInsert, читающий результат RETURNING: scan вызывается для каждой строки результата
+func bulkQuery(tx *sql.Tx, insert, suffix string, rows [][]any, scan func(*sql.Rows) error) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	perQuery := maxQueryParams / len(rows[0])
+	for start := 0; start < len(rows); start += perQuery {
+		chunk := rows[start:min(start+perQuery, len(rows))]
+
+		var sb strings.Builder
+		sb.WriteString(insert)
+		sb.WriteString(" VALUES ")
+		args := make([]any, 0, len(chunk)*len(chunk[0]))
+		for i, row := range chunk {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteByte('(')
+			for j, v := range row {
+				if j > 0 {
+					sb.WriteString(", ")
+				}
+				args = append(args, v)
+				fmt.Fprintf(&sb, "$%d", len(args))
+			}
+			sb.WriteByte(')')
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(suffix)
+
+		if scan == nil {
+			if _, err := tx.Exec(sb.String(), args...); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := queryRows(tx, sb.String(), args, scan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func queryRows(tx *sql.Tx, query string, args []any, scan func(*sql.Rows) error) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
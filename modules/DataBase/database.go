@@ -3,17 +3,24 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"project_wb_l0/modules/consumer"
 	"project_wb_l0/modules/general"
+	"project_wb_l0/modules/retry"
 	"sync"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type Db struct {
-	db *sql.DB
+	db         *sql.DB
+	connStr    string
+	instanceID string // отличает уведомления этого экземпляра от чужих
+	writeHooks []func(general.Order)
+	retry      retry.Policy // повтор записи при временных ошибках
+	ingest     ingestCounters
 }
 
 // инициализируем базу данных и подключение к ней
@@ -35,7 +42,7 @@ func InitBd(ctx context.Context, user, password, name, host, port string) (*Db,
 		return nil, fmt.Errorf("ошибка подключения к БД: %w", err)
 	}
 	log.Println("Запускаемся")
-	database := &Db{db: db}
+	database := &Db{db: db, connStr: connStr, instanceID: newInstanceID(), retry: retry.Policy{Attempts: 1}}
 	return database, nil
 
 }
@@ -49,6 +56,24 @@ func (db *Db) StartListeningFromKafkaToWrite(ctx context.Context, fetchers ...co
 	go db.listenFromKafkaToWrite(ctx, fetchers...)
 }
 
+// OnOrderWritten регистрирует функцию, вызываемую после успешной записи заказа из Kafka.
+// Регистрировать нужно до StartListeningFromKafkaToWrite
+func (db *Db) OnOrderWritten(hook func(general.Order)) {
+	if db == nil {
+		return
+	}
+	db.writeHooks = append(db.writeHooks, hook)
+}
+
+// SetRetryPolicy задаёт повтор записи заказов из Kafka при временных ошибках бд.
+// Вызывать нужно до StartListeningFromKafkaToWrite
+func (db *Db) SetRetryPolicy(p retry.Policy) {
+	if db == nil {
+		return
+	}
+	db.retry = p
+}
+
 // Слушаем и обрабатываем информацию с нескольких консюмеров
 func (db *Db) listenFromKafkaToWrite(ctx context.Context, fetchers ...consumer.Registration) {
 	var wg sync.WaitGroup
@@ -65,14 +90,14 @@ func (db *Db) listenFromKafkaToWrite(ctx context.Context, fetchers ...consumer.R
 						return
 					}
 
-					log.Println("Получили данные")
-					log.Println(recievedData.Payment)
-					err := db.writeOrder2Bd(recievedData)
+					log.Printf("Получили пачку из %d заказов\n", len(recievedData))
+					answer := db.writeBatch(ctx, recievedData)
 
 					select {
-					case fetcher.RecieveAnswer() <- consumer.Answer{Err: err}:
-					default:
-						log.Println("Не могу отправить ответ: канал занят или закрыт")
+					case fetcher.RecieveAnswer() <- answer:
+					case <-ctx.Done():
+						log.Println("Не могу отправить ответ: сервис останавливается")
+						return
 					}
 				case <-ctx.Done():
 					log.Println("горутина обрабатывающая фетчеры покидает это все дело ")
@@ -87,166 +112,143 @@ func (db *Db) listenFromKafkaToWrite(ctx context.Context, fetchers ...consumer.R
 	}()
 }
 
-// Запись заказа в базу данных
-func (d *Db) writeOrder2Bd(order general.Order) error {
-	tx, err := d.db.Begin()
-	if err != nil {
-		return fmt.Errorf("ошибка начала транзакции: %w", err)
+// recordWriter записывает записи одного вида одной транзакцией
+// и возвращает записанные заказы (для хуков записи)
+type recordWriter func(records []consumer.Record) ([]general.Order, error)
+
+// writerFor выбирает запись для вида записей
+func (db *Db) writerFor(kind consumer.Kind) (recordWriter, error) {
+	switch kind {
+	case consumer.KindOrder:
+		return db.writeOrders2Bd, nil
+	case consumer.KindStatus:
+		return db.writeStatuses, nil
+	case consumer.KindCancel:
+		return db.writeCancels, nil
 	}
-	// Откатываемся, если появилась ошибка
-	defer tx.Rollback()
+	return nil, fmt.Errorf("неизвестный вид записи %q", kind)
+}
 
-	// 1. Сохраняем Delivery
-	_, err = tx.Exec(`
-        INSERT INTO Delivery (name, phone, zip, city, address, region, email)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
-        ON CONFLICT (name) DO UPDATE SET 
-            phone = EXCLUDED.phone,
-            zip = EXCLUDED.zip,
-            city = EXCLUDED.city,
-            address = EXCLUDED.address,
-            region = EXCLUDED.region,
-            email = EXCLUDED.email
-        `,
-		order.Delivery.Name,
-		order.Delivery.Phone,
-		order.Delivery.Zip,
-		order.Delivery.City,
-		order.Delivery.Address,
-		order.Delivery.Region,
-		order.Delivery.Email,
-	)
-	if err != nil {
-		return fmt.Errorf("ошибка сохранения Delivery: %w", err)
+// writeBatch раскладывает пачку по видам записей (заказы, статусы, отмены)
+// и записывает каждый вид своей транзакцией. Ответ собирается по исходным позициям записей
+func (db *Db) writeBatch(ctx context.Context, batch []consumer.Record) consumer.Answer {
+	groups := groupByKind(batch)
+	answers := make(map[consumer.Kind]consumer.Answer, len(groups.kinds))
+	for _, kind := range groups.kinds {
+		records := make([]consumer.Record, len(groups.index[kind]))
+		for j, i := range groups.index[kind] {
+			records[j] = batch[i]
+		}
+		write, err := db.writerFor(kind)
+		if err != nil {
+			answers[kind] = consumer.Answer{Err: err}
+		} else {
+			answers[kind] = db.writeRecords(ctx, write, records)
+		}
 	}
+	return mergeAnswers(len(batch), groups, answers)
+}
 
-	// 2. Сохраняем Payment
-	_, err = tx.Exec(`
-        INSERT INTO Payment (
-            transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
-        ) VALUES (
-            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
-        ) ON CONFLICT (transaction) DO UPDATE SET
-		 request_id = EXCLUDED.request_id,
-        currency = EXCLUDED.currency,
-        provider = EXCLUDED.provider,
-        amount = EXCLUDED.amount,
-        payment_dt = EXCLUDED.payment_dt,
-        bank = EXCLUDED.bank,
-        delivery_cost = EXCLUDED.delivery_cost,
-        goods_total = EXCLUDED.goods_total,
-        custom_fee = EXCLUDED.custom_fee
-    `,
-		order.Payment.Transaction,
-		order.Payment.RequestID,
-		order.Payment.Currency,
-		order.Payment.Provider,
-		order.Payment.Amount,
-		order.Payment.PaymentDT,
-		order.Payment.Bank,
-		order.Payment.DeliveryCost,
-		order.Payment.GoodsTotal,
-		order.Payment.CustomFee,
-	)
-	if err != nil {
-		return fmt.Errorf("ошибка сохранения Payment: %w", err)
-	}
-
-	// 3. Сохраняем Items
-	for _, item := range order.Items {
-		_, err = tx.Exec(`
-            INSERT INTO Items (
-                chrt_id, price, rid, name, sale, total_price, nm_id, brand, status
-            ) VALUES (
-                $1, $2, $3, $4, $5, $6, $7, $8, $9
-            ) ON CONFLICT (chrt_id) DO UPDATE SET
-			price = EXCLUDED.price,
-			rid = EXCLUDED.rid,
-			name = EXCLUDED.name,
-			sale = EXCLUDED.sale,
-			total_price = EXCLUDED.total_price,
-			nm_id = EXCLUDED.nm_id,
-			brand = EXCLUDED.brand,
-			status = EXCLUDED.status 
-			`,
-			item.ChrtID,
-			item.Price,
-			item.Rid,
-			item.Name,
-			item.Sale,
-			item.TotalPrice,
-			item.NmID,
-			item.Brand,
-			item.Status,
-		)
-		if err != nil {
-			return fmt.Errorf("ошибка сохранения Item: %w", err)
+// kindGroups — позиции записей пачки по видам, виды в порядке первого появления
+type kindGroups struct {
+	kinds []consumer.Kind
+	index map[consumer.Kind][]int
+}
+
+// groupByKind раскладывает позиции записей пачки по видам
+func groupByKind(batch []consumer.Record) kindGroups {
+	g := kindGroups{index: make(map[consumer.Kind][]int)}
+	for i, rec := range batch {
+		if _, ok := g.index[rec.Kind]; !ok {
+			g.kinds = append(g.kinds, rec.Kind)
 		}
+		g.index[rec.Kind] = append(g.index[rec.Kind], i)
 	}
+	return g
+}
 
-	// 4. Сохраняем сам Order
-	_, err = tx.Exec(`
-        INSERT INTO Orders (
-            order_uid, entry, delivery_id, payment_id, track_number, locale, internal_signature,
-            customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
-        ) VALUES (
-            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
-        ) ON CONFLICT (order_uid) DO UPDATE SET
-		entry = EXCLUDED.entry,
-		delivery_id = EXCLUDED.delivery_id,
-		payment_id = EXCLUDED.payment_id,
-		track_number = EXCLUDED.track_number,
-		locale = EXCLUDED.locale,
-		internal_signature = EXCLUDED.internal_signature,
-		customer_id = EXCLUDED.customer_id,
-		delivery_service = EXCLUDED.delivery_service,
-		shardkey = EXCLUDED.shardkey,
-		sm_id = EXCLUDED.sm_id,
-		date_created = EXCLUDED.date_created,
-		oof_shard = EXCLUDED.oof_shard
-	`,
-		order.OrderUID,
-		order.Entry,
-		order.Delivery.Name,
-		order.Payment.Transaction,
-		order.TrackNumber,
-		order.Locale,
-		order.InternalSignature,
-		order.CustomerID,
-		order.DeliveryService,
-		order.Shardkey,
-		order.SmID,
-		order.DateCreated,
-		order.OofShard,
-	)
-	if err != nil {
-		return fmt.Errorf("ошибка сохранения Order: %w", err)
-	}
-
-	// 5. Связываем Order и Items через Order_contents
-	for _, item := range order.Items {
-		_, err = tx.Exec(`
-            INSERT INTO Order_contents (order_uid, chrt_id)
-            VALUES ($1, $2)
-            ON CONFLICT (order_uid, chrt_id) DO UPDATE SET
-			order_uid = EXCLUDED.order_uid,
-			chrt_id = EXCLUDED.chrt_id`,
-			order.OrderUID,
-			item.ChrtID,
-		)
+// mergeAnswers собирает ответы по видам в ответ на всю пачку из size записей:
+// j-й результат вида попадает на исходную позицию его j-й записи.
+// Err ответа — первая ошибка по порядку записей пачки
+func mergeAnswers(size int, g kindGroups, answers map[consumer.Kind]consumer.Answer) consumer.Answer {
+	failed := false
+	for _, kind := range g.kinds {
+		failed = failed || answers[kind].Err != nil
+	}
+	if !failed {
+		return consumer.Answer{}
+	}
+	if len(g.kinds) == 1 {
+		return answers[g.kinds[0]]
+	}
+
+	answer := consumer.Answer{Errs: make([]error, size)}
+	for _, kind := range g.kinds {
+		for j, i := range g.index[kind] {
+			answer.Errs[i] = answers[kind].ErrFor(j)
+		}
+	}
+	for _, err := range answer.Errs {
 		if err != nil {
-			return fmt.Errorf("ошибка сохранения Order_contents: %w", err)
+			answer.Err = err
+			break
 		}
 	}
+	return answer
+}
 
-	// Завершаем транзакцию
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("ошибка завершения транзакции: %w", err)
+// writeRecords записывает записи одного вида одной транзакцией, повторяя запись при временных ошибках.
+// Если записи не записались из-за постоянной ошибки, они записываются по одной,
+// чтобы одна плохая запись не блокировала остальные
+func (db *Db) writeRecords(ctx context.Context, write recordWriter, batch []consumer.Record) consumer.Answer {
+	var written []general.Order
+	err := db.withRetry(ctx, func() (err error) {
+		written, err = write(batch)
+		return err
+	})
+	if err == nil {
+		db.runWriteHooks(written...)
+		return consumer.Answer{}
+	}
+	if errors.Is(err, retry.ErrExhausted) || ctx.Err() != nil {
+		// бд недоступна — писать по одной бессмысленно
+		log.Printf("Пачку не удалось записать: %v\n", err)
+		return consumer.Answer{Err: err}
 	}
+	log.Printf("Пачку не удалось записать целиком, пишем по одной записи: %v\n", err)
+
+	answer := consumer.Answer{Err: err, Errs: make([]error, len(batch))}
+	for i := range batch {
+		answer.Errs[i] = db.withRetry(ctx, func() (err error) {
+			written, err = write(batch[i : i+1])
+			return err
+		})
+		if answer.Errs[i] == nil {
+			db.runWriteHooks(written...)
+		}
+	}
+	return answer
+}
 
-	log.Println("Заказ успешно записан в БД")
-	return nil
+// withRetry выполняет запись, повторяя её с задержкой, пока ошибка временная
+func (db *Db) withRetry(ctx context.Context, write func() error) error {
+	attempt := 0
+	return retry.Do(ctx, db.retry, isTransient, func() error {
+		attempt++
+		err := write()
+		if err != nil && isTransient(err) && attempt < db.retry.Attempts {
+			log.Printf("Временная ошибка записи в бд (попытка %d из %d): %v\n", attempt, db.retry.Attempts, err)
+		}
+		return err
+	})
+}
+
+func (db *Db) runWriteHooks(orders ...general.Order) {
+	for _, order := range orders {
+		for _, hook := range db.writeHooks {
+			hook(order)
+		}
+	}
 }
 
 // Получаем информацию о заказе с UID заказа
@@ -272,6 +274,9 @@ func (d *Db) GetOrderByUID(uid string, order *general.Order) error {
 		&order.DateCreated,
 		&order.OofShard,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("не найдено в Orders: %w", general.ErrOrderNotFound)
+	}
 	if err != nil {
 		return fmt.Errorf("не найдено в Orders: %w", err)
 	}
@@ -351,6 +356,103 @@ func (d *Db) GetOrderByUID(uid string, order *general.Order) error {
 	return nil
 }
 
+// Получаем сразу несколько заказов одним запросом.
+// Заказы, которых нет в БД, в результат не попадают
+func (d *Db) GetOrdersByUIDs(uids []string) (map[string]general.Order, error) {
+	rows, err := d.db.Query(`SELECT
+							o.order_uid, o.entry, o.track_number, o.locale, o.internal_signature,
+							o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+							d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+							p.transaction, p.request_id, p.currency, p.provider, p.amount,
+							p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee,
+							i.chrt_id, i.price, i.rid, i.name, i.sale, i.total_price,
+							i.nm_id, i.brand, i.status
+						FROM Orders o
+						JOIN Delivery d ON d.name = o.delivery_id
+						JOIN Payment p ON p.transaction = o.payment_id
+						LEFT JOIN Order_contents oc ON oc.order_uid = o.order_uid
+						LEFT JOIN Items i ON i.chrt_id = oc.chrt_id
+						WHERE o.order_uid = ANY($1)`, pq.Array(uids))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки заказов: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make(map[string]general.Order, len(uids))
+	for rows.Next() {
+		var (
+			order general.Order
+			// Колонки Items пустые, если у заказа нет позиций
+			chrtID, rid, name, nmID, brand sql.NullString
+			price, totalPrice              sql.NullFloat64
+			sale, status                   sql.NullInt64
+		)
+		if err := rows.Scan(
+			&order.OrderUID,
+			&order.Entry,
+			&order.TrackNumber,
+			&order.Locale,
+			&order.InternalSignature,
+			&order.CustomerID,
+			&order.DeliveryService,
+			&order.Shardkey,
+			&order.SmID,
+			&order.DateCreated,
+			&order.OofShard,
+			&order.Delivery.Name,
+			&order.Delivery.Phone,
+			&order.Delivery.Zip,
+			&order.Delivery.City,
+			&order.Delivery.Address,
+			&order.Delivery.Region,
+			&order.Delivery.Email,
+			&order.Payment.Transaction,
+			&order.Payment.RequestID,
+			&order.Payment.Currency,
+			&order.Payment.Provider,
+			&order.Payment.Amount,
+			&order.Payment.PaymentDT,
+			&order.Payment.Bank,
+			&order.Payment.DeliveryCost,
+			&order.Payment.GoodsTotal,
+			&order.Payment.CustomFee,
+			&chrtID,
+			&price,
+			&rid,
+			&name,
+			&sale,
+			&totalPrice,
+			&nmID,
+			&brand,
+			&status,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования заказа: %w", err)
+		}
+
+		if existing, ok := orders[order.OrderUID]; ok {
+			order = existing
+		}
+		if chrtID.Valid {
+			order.Items = append(order.Items, general.Item{
+				ChrtID:     chrtID.String,
+				Price:      price.Float64,
+				Rid:        rid.String,
+				Name:       name.String,
+				Sale:       int(sale.Int64),
+				TotalPrice: totalPrice.Float64,
+				NmID:       nmID.String,
+				Brand:      brand.String,
+				Status:     int(status.Int64),
+			})
+		}
+		orders[order.OrderUID] = order
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении заказов: %w", err)
+	}
+	return orders, nil
+}
+
 // Сохраняем UID в HASH
 func (d *Db) SaveOrderToCacheBd(uid string) error {
 
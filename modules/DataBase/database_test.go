@@ -0,0 +1,97 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"project_wb_l0/modules/consumer"
+)
+
+func TestGroupByKind(t *testing.T) {
+	batch := []consumer.Record{
+		{Kind: consumer.KindStatus}, {Kind: consumer.KindOrder}, {Kind: consumer.KindStatus},
+		{Kind: consumer.KindCancel}, {Kind: consumer.KindOrder},
+	}
+	g := groupByKind(batch)
+	if want := []consumer.Kind{consumer.KindStatus, consumer.KindOrder, consumer.KindCancel}; !reflect.DeepEqual(g.kinds, want) {
+		t.Fatalf("виды %v, ожидалось %v", g.kinds, want)
+	}
+	want := map[consumer.Kind][]int{
+		consumer.KindStatus: {0, 2},
+		consumer.KindOrder:  {1, 4},
+		consumer.KindCancel: {3},
+	}
+	if !reflect.DeepEqual(g.index, want) {
+		t.Fatalf("позиции %v, ожидалось %v", g.index, want)
+	}
+}
+
+func TestMergeAnswers(t *testing.T) {
+	errOrder := errors.New("заказ не записан")
+	errStatus := errors.New("статус не записан")
+	errBatch := errors.New("пачка не записана")
+
+	// Пачка: статус, заказ, статус, отмена, заказ
+	batch := []consumer.Record{
+		{Kind: consumer.KindStatus}, {Kind: consumer.KindOrder}, {Kind: consumer.KindStatus},
+		{Kind: consumer.KindCancel}, {Kind: consumer.KindOrder},
+	}
+	g := groupByKind(batch)
+
+	tests := []struct {
+		name     string
+		answers  map[consumer.Kind]consumer.Answer
+		wantErr  error
+		wantErrs []error
+	}{
+		{
+			name:    "всё записано",
+			answers: map[consumer.Kind]consumer.Answer{},
+		},
+		{
+			name: "заказы записаны по одной",
+			answers: map[consumer.Kind]consumer.Answer{
+				consumer.KindOrder: {Err: errBatch, Errs: []error{nil, errOrder}},
+			},
+			wantErr:  errOrder,
+			wantErrs: []error{nil, nil, nil, nil, errOrder},
+		},
+		{
+			name: "статусы не записаны целиком",
+			answers: map[consumer.Kind]consumer.Answer{
+				consumer.KindStatus: {Err: errStatus},
+				consumer.KindOrder:  {Err: errBatch, Errs: []error{errOrder, nil}},
+			},
+			wantErr:  errStatus,
+			wantErrs: []error{errStatus, errOrder, errStatus, nil, nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeAnswers(len(batch), g, tt.answers)
+			if got.Err != tt.wantErr || !reflect.DeepEqual(got.Errs, tt.wantErrs) {
+				t.Fatalf("ответ %+v, ожидалось Err=%v Errs=%v", got, tt.wantErr, tt.wantErrs)
+			}
+			for i := range batch {
+				want := tt.answers[batch[i].Kind].Err
+				if tt.wantErrs != nil {
+					want = tt.wantErrs[i]
+				}
+				if got.ErrFor(i) != want {
+					t.Fatalf("ErrFor(%d) = %v, ожидалось %v", i, got.ErrFor(i), want)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeAnswersSingleKind(t *testing.T) {
+	errBatch := errors.New("пачка не записана")
+	g := groupByKind([]consumer.Record{{Kind: consumer.KindOrder}, {Kind: consumer.KindOrder}})
+	answer := consumer.Answer{Err: errBatch, Errs: []error{nil, errBatch}}
+	// С одним видом ответ возвращается как есть, позиции уже совпадают
+	if got := mergeAnswers(2, g, map[consumer.Kind]consumer.Answer{consumer.KindOrder: answer}); !reflect.DeepEqual(got, answer) {
+		t.Fatalf("ответ %+v, ожидалось %+v", got, answer)
+	}
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"project_wb_l0/modules/consumer"
+	"time"
+)
+
+// FinanceReview — сохранённое расхождение сумм заказа
+type FinanceReview struct {
+	OrderUID string `json:"order_uid"`
+	consumer.FinanceMismatch
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// FinanceMismatches возвращает последние limit расхождений сумм, новые первыми
+func (d *Db) FinanceMismatches(ctx context.Context, limit int) ([]FinanceReview, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("база данных не инициализирована")
+	}
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT order_uid, check_name, path, COALESCE(currency, ''), expected, actual, tolerance, detected_at
+		FROM Finance_mismatches
+		ORDER BY detected_at DESC, id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения Finance_mismatches: %w", err)
+	}
+	defer rows.Close()
+
+	var out []FinanceReview
+	for rows.Next() {
+		var r FinanceReview
+		err := rows.Scan(&r.OrderUID, &r.Check, &r.Path, &r.Currency,
+			&r.Expected, &r.Actual, &r.Tolerance, &r.DetectedAt)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения Finance_mismatches: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
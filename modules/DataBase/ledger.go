@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"project_wb_l0/modules/general"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Журнал обработанных заказов (таблица Processed_orders) хранит для каждого order_uid
+// последнюю записанную версию — date_created заказа. Заказ записывается, только если
+// его версия строго новее записанной: повторная доставка того же сообщения
+// и запоздавшие старые версии отбрасываются
+
+// ingestCounters — счётчики записи заказов из Kafka
+type ingestCounters struct {
+	written    atomic.Int64
+	duplicates atomic.Int64
+	stale      atomic.Int64
+	// События заказов из топиков статусов и отмен
+	statuses      atomic.Int64
+	cancels       atomic.Int64
+	droppedEvents atomic.Int64
+}
+
+// droppedOrders — сколько заказов пачки отброшено журналом
+type droppedOrders struct {
+	duplicates int // та же версия уже записана
+	stale      int // записана более новая версия
+}
+
+func (c *ingestCounters) add(written int, dropped droppedOrders) {
+	c.written.Add(int64(written))
+	c.duplicates.Add(int64(dropped.duplicates))
+	c.stale.Add(int64(dropped.stale))
+}
+
+// IngestStats — статистика записи заказов из Kafka
+type IngestStats struct {
+	Written           int64 `json:"written"`
+	DroppedDuplicates int64 `json:"dropped_duplicates"`
+	DroppedStale      int64 `json:"dropped_stale"`
+	StatusUpdates     int64 `json:"status_updates"`
+	Cancellations     int64 `json:"cancellations"`
+	// Смены статуса старее записанной или пришедшие после отмены, повторные отмены
+	DroppedEvents int64 `json:"dropped_events"`
+}
+
+// IngestStats возвращает статистику записи заказов из Kafka
+func (d *Db) IngestStats() IngestStats {
+	if d == nil {
+		return IngestStats{}
+	}
+	return IngestStats{
+		Written:           d.ingest.written.Load(),
+		DroppedDuplicates: d.ingest.duplicates.Load(),
+		DroppedStale:      d.ingest.stale.Load(),
+		StatusUpdates:     d.ingest.statuses.Load(),
+		Cancellations:     d.ingest.cancels.Load(),
+		DroppedEvents:     d.ingest.droppedEvents.Load(),
+	}
+}
+
+// orderVersion — версия заказа для журнала. Postgres хранит время с точностью
+// до микросекунд, поэтому и сравниваем с той же точностью
+func orderVersion(order general.Order) time.Time {
+	return order.DateCreated.Truncate(time.Microsecond)
+}
+
+// acceptNewVersions отмечает в журнале заказы пачки и возвращает индексы тех из них,
+// которые нужно записать: по одному на order_uid, самую новую версию,
+// и только если она новее записанной. Строки журнала остаются заблокированными
+// до конца транзакции, так что параллельная запись того же заказа подождёт
+func (d *Db) acceptNewVersions(tx *sql.Tx, orders []general.Order) ([]int, droppedOrders, error) {
+	var dropped droppedOrders
+
+	// Внутри пачки оставляем самую новую версию каждого заказа
+	newest := make(map[string]int, len(orders))
+	var uids []string
+	for i, order := range orders {
+		j, ok := newest[order.OrderUID]
+		if !ok {
+			newest[order.OrderUID] = i
+			uids = append(uids, order.OrderUID)
+			continue
+		}
+		switch cur := orderVersion(orders[j]); {
+		case orderVersion(order).After(cur):
+			newest[order.OrderUID] = i
+			dropped.stale++
+		case orderVersion(order).Equal(cur):
+			dropped.duplicates++
+		default:
+			dropped.stale++
+		}
+	}
+
+	rows := make([][]any, len(uids))
+	for i, uid := range uids {
+		rows[i] = []any{uid, orderVersion(orders[newest[uid]])}
+	}
+	accepted := make(map[string]bool, len(uids))
+	err := bulkQuery(tx, `INSERT INTO Processed_orders (order_uid, version)`,
+		`ON CONFLICT (order_uid) DO UPDATE SET
+			version = EXCLUDED.version,
+			processed_at = now()
+		WHERE Processed_orders.version < EXCLUDED.version
+		RETURNING order_uid`, rows, func(r *sql.Rows) error {
+			var uid string
+			if err := r.Scan(&uid); err != nil {
+				return err
+			}
+			accepted[uid] = true
+			return nil
+		})
+	if err != nil {
+		return nil, dropped, err
+	}
+
+	var rejected []string
+	for _, uid := range uids {
+		if !accepted[uid] {
+			rejected = append(rejected, uid)
+		}
+	}
+	if len(rejected) > 0 {
+		// Отличаем повторы от устаревших версий для статистики
+		err := queryRows(tx, `SELECT order_uid, version FROM Processed_orders WHERE order_uid = ANY($1)`,
+			[]any{pq.Array(rejected)}, func(r *sql.Rows) error {
+				var uid string
+				var version time.Time
+				if err := r.Scan(&uid, &version); err != nil {
+					return err
+				}
+				if version.Equal(orderVersion(orders[newest[uid]])) {
+					dropped.duplicates++
+				} else {
+					dropped.stale++
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, dropped, err
+		}
+	}
+
+	out := make([]int, 0, len(accepted))
+	for _, uid := range uids {
+		if accepted[uid] {
+			out = append(out, newest[uid])
+		}
+	}
+	return out, dropped, nil
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OrderUpdatesChannel — канал Postgres NOTIFY, в который writeOrders2Bd
+// сообщает о каждом записанном заказе
+const OrderUpdatesChannel = "order_updated"
+
+// orderUpdate — полезная нагрузка уведомления об изменении заказа
+type orderUpdate struct {
+	Instance string `json:"instance"`
+	OrderUID string `json:"order_uid"`
+}
+
+// OrderUpdateHandler получает уведомления об изменении заказов другими экземплярами сервиса
+type OrderUpdateHandler interface {
+	// OrderUpdated вызывается, когда заказ uid перезаписан другим экземпляром
+	OrderUpdated(uid string)
+	// UpdatesMissed вызывается после переподключения слушателя:
+	// уведомления за время разрыва могли быть потеряны
+	UpdatesMissed()
+}
+
+// notifyOrdersUpdated ставит в транзакцию уведомления об изменении нескольких заказов
+func (d *Db) notifyOrdersUpdated(tx *sql.Tx, uids []string) error {
+	payloads := make([]string, len(uids))
+	for i, uid := range uids {
+		payload, err := json.Marshal(orderUpdate{Instance: d.instanceID, OrderUID: uid})
+		if err != nil {
+			return err
+		}
+		payloads[i] = string(payload)
+	}
+	_, err := tx.Exec(`SELECT pg_notify($1, p) FROM unnest($2::text[]) AS p`,
+		OrderUpdatesChannel, pq.Array(payloads))
+	return err
+}
+
+// ListenOrderUpdates подписывается на уведомления об изменении заказов и передаёт их handler.
+// Собственные уведомления этого экземпляра пропускаются. Обрыв соединения
+// восстанавливается автоматически; слушатель работает, пока не отменён ctx
+func (d *Db) ListenOrderUpdates(ctx context.Context, handler OrderUpdateHandler) {
+	if d == nil || d.connStr == "" {
+		log.Println("База данных не инициализирована")
+		return
+	}
+	listener := pq.NewListener(d.connStr, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventConnected:
+			log.Println("Слушатель уведомлений о заказах подключён")
+		case pq.ListenerEventDisconnected:
+			log.Printf("Слушатель уведомлений о заказах отключился: %v", err)
+		case pq.ListenerEventReconnected:
+			log.Println("Слушатель уведомлений о заказах переподключён")
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("Не удалось подключить слушатель уведомлений о заказах: %v", err)
+		}
+	})
+	go d.listenOrderUpdates(ctx, listener, handler)
+}
+
+func (d *Db) listenOrderUpdates(ctx context.Context, listener *pq.Listener, handler OrderUpdateHandler) {
+	// Закрытие слушателя прерывает и ожидание подключения в Listen
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	// Listen ждёт первого успешного подключения
+	if err := listener.Listen(OrderUpdatesChannel); err != nil {
+		log.Printf("Не удалось подписаться на %s: %v", OrderUpdatesChannel, err)
+		return
+	}
+
+	// Периодический ping помогает быстрее заметить оборванное соединение
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case n, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				// Соединение восстановлено, часть уведомлений могла потеряться
+				handler.UpdatesMissed()
+				continue
+			}
+			var upd orderUpdate
+			if err := json.Unmarshal([]byte(n.Extra), &upd); err != nil {
+				log.Printf("Некорректное уведомление в %s: %v", n.Channel, err)
+				continue
+			}
+			if upd.Instance == d.instanceID {
+				continue
+			}
+			handler.OrderUpdated(upd.OrderUID)
+		case <-ping.C:
+			go listener.Ping()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// newInstanceID генерирует случайный идентификатор экземпляра сервиса для уведомлений
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("pid-%d", os.Getpid())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"project_wb_l0/modules/consumer"
+	"project_wb_l0/modules/general"
+	"time"
+)
+
+// Текущий статус заказа хранится в Order_status, отмены — в Order_cancellations.
+// Смена статуса записывается, только если она новее записанной. Отмена окончательна:
+// после неё смены статуса отбрасываются, а повторная отмена ничего не меняет.
+// Статус может прийти раньше самого заказа, поэтому ссылок на Orders нет
+
+// StatusInfo — текущий статус заказа
+type StatusInfo struct {
+	OrderUID     string    `json:"order_uid"`
+	Status       string    `json:"status"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	CancelReason string    `json:"cancel_reason,omitempty"`
+}
+
+// writeStatuses записывает смены статусов одной транзакцией.
+// Внутри пачки для каждого заказа остаётся самая новая смена
+func (d *Db) writeStatuses(records []consumer.Record) ([]general.Order, error) {
+	newest := make(map[string]general.OrderStatus, len(records))
+	var uids []string
+	for _, rec := range records {
+		st := rec.Status
+		cur, ok := newest[st.OrderUID]
+		if !ok {
+			uids = append(uids, st.OrderUID)
+		}
+		if !ok || st.UpdatedAt.After(cur.UpdatedAt) {
+			newest[st.OrderUID] = st
+		}
+	}
+	rows := make([][]any, len(uids))
+	for i, uid := range uids {
+		rows[i] = []any{uid, newest[uid].Status, newest[uid].UpdatedAt}
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	// Откатываемся, если появилась ошибка
+	defer tx.Rollback()
+
+	written := 0
+	err = bulkQuery(tx, `INSERT INTO Order_status (order_uid, status, updated_at)`,
+		`ON CONFLICT (order_uid) DO UPDATE SET
+			status = EXCLUDED.status,
+			updated_at = EXCLUDED.updated_at
+		WHERE Order_status.status <> '`+general.StatusCancelled+`'
+			AND Order_status.updated_at < EXCLUDED.updated_at
+		RETURNING order_uid`, rows, func(r *sql.Rows) error {
+			written++
+			var uid string
+			return r.Scan(&uid)
+		})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения Order_status: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка завершения транзакции: %w", err)
+	}
+
+	d.ingest.statuses.Add(int64(written))
+	d.ingest.droppedEvents.Add(int64(len(records) - written))
+	log.Printf("Записано смен статуса: %d из %d", written, len(records))
+	return nil, nil
+}
+
+// writeCancels записывает отмены заказов одной транзакцией: причину в Order_cancellations
+// и статус cancelled в Order_status. Внутри пачки для каждого заказа остаётся первая отмена
+func (d *Db) writeCancels(records []consumer.Record) ([]general.Order, error) {
+	seen := make(map[string]bool, len(records))
+	var cancels, statuses [][]any
+	for _, rec := range records {
+		c := rec.Cancel
+		if seen[c.OrderUID] {
+			continue
+		}
+		seen[c.OrderUID] = true
+		cancels = append(cancels, []any{c.OrderUID, c.Reason, c.CancelledAt})
+		statuses = append(statuses, []any{c.OrderUID, general.StatusCancelled, c.CancelledAt})
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	// Откатываемся, если появилась ошибка
+	defer tx.Rollback()
+
+	written := 0
+	err = bulkQuery(tx, `INSERT INTO Order_cancellations (order_uid, reason, cancelled_at)`,
+		`ON CONFLICT (order_uid) DO NOTHING
+		RETURNING order_uid`, cancels, func(r *sql.Rows) error {
+			written++
+			var uid string
+			return r.Scan(&uid)
+		})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения Order_cancellations: %w", err)
+	}
+
+	err = bulkInsert(tx, `INSERT INTO Order_status (order_uid, status, updated_at)`,
+		`ON CONFLICT (order_uid) DO UPDATE SET
+			status = EXCLUDED.status,
+			updated_at = EXCLUDED.updated_at
+		WHERE Order_status.status <> EXCLUDED.status`, statuses)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения Order_status: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка завершения транзакции: %w", err)
+	}
+
+	d.ingest.cancels.Add(int64(written))
+	d.ingest.droppedEvents.Add(int64(len(records) - written))
+	log.Printf("Записано отмен заказов: %d из %d", written, len(records))
+	return nil, nil
+}
+
+// OrderStatus возвращает текущий статус заказа.
+// Если статус ещё не приходил, возвращает general.ErrOrderNotFound
+func (d *Db) OrderStatus(ctx context.Context, uid string) (StatusInfo, error) {
+	if d == nil || d.db == nil {
+		return StatusInfo{}, fmt.Errorf("база данных не инициализирована")
+	}
+	var info StatusInfo
+	var reason sql.NullString
+	err := d.db.QueryRowContext(ctx, `
+		SELECT s.order_uid, s.status, s.updated_at, c.reason
+		FROM Order_status s
+		LEFT JOIN Order_cancellations c ON c.order_uid = s.order_uid
+		WHERE s.order_uid = $1`, uid).Scan(&info.OrderUID, &info.Status, &info.UpdatedAt, &reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return StatusInfo{}, fmt.Errorf("нет статуса в Order_status: %w", general.ErrOrderNotFound)
+	}
+	if err != nil {
+		return StatusInfo{}, fmt.Errorf("ошибка чтения Order_status: %w", err)
+	}
+	info.CancelReason = reason.String
+	return info, nil
+}
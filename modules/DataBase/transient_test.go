@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"обрыв соединения 08006", &pq.Error{Code: "08006"}, true},
+		{"serialization_failure 40001", &pq.Error{Code: "40001"}, true},
+		{"deadlock_detected 40P01", &pq.Error{Code: "40P01"}, true},
+		{"too_many_connections 53300", &pq.Error{Code: "53300"}, true},
+		{"lock_not_available 55P03", &pq.Error{Code: "55P03"}, true},
+		{"admin_shutdown 57P01", &pq.Error{Code: "57P01"}, true},
+		{"cannot_connect_now 57P03", &pq.Error{Code: "57P03"}, true},
+		{"query_canceled 57014", &pq.Error{Code: "57014"}, false},
+		{"unique_violation 23505", &pq.Error{Code: "23505"}, false},
+		{"invalid_text_representation 22P02", &pq.Error{Code: "22P02"}, false},
+		{"обёрнутая ошибка pq", fmt.Errorf("запись: %w", &pq.Error{Code: "40P01"}), true},
+		{"driver.ErrBadConn", driver.ErrBadConn, true},
+		{"EOF", io.EOF, true},
+		{"ErrUnexpectedEOF", fmt.Errorf("чтение: %w", io.ErrUnexpectedEOF), true},
+		{"ECONNREFUSED", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{"ECONNRESET", syscall.ECONNRESET, true},
+		{"сетевая ошибка", &net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		{"отмена контекста", context.Canceled, false},
+		{"произвольная ошибка", errors.New("неверные данные"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Fatalf("isTransient(%v) = %v, ожидалось %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testSchema = `{
+  "type": "record",
+  "name": "Test",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "count", "type": "long"},
+    {"name": "price", "type": "double"},
+    {"name": "at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+    {"name": "note", "type": ["null", "string"]},
+    {"name": "tags", "type": {"type": "map", "values": "string"}},
+    {"name": "items", "type": {"type": "array", "items": {
+      "type": "record",
+      "name": "Item",
+      "fields": [{"name": "id", "type": "string"}, {"name": "qty", "type": "int"}]
+    }}}
+  ]
+}`
+
+func mustParse(t testing.TB, s string) *Schema {
+	t.Helper()
+	schema, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return schema
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := mustParse(t, testSchema)
+	at := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC)
+	in := map[string]any{
+		"name":  "заказ",
+		"count": int64(-42),
+		"price": 99.5,
+		"at":    at,
+		"note":  "срочно",
+		"tags":  map[string]any{"a": "b"},
+		"items": []any{
+			map[string]any{"id": "x", "qty": int32(2)},
+			map[string]any{"id": "y", "qty": int32(0)},
+		},
+	}
+	data, err := s.Encode(in)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	out, err := s.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("после кодирования и декодирования\nполучено %#v\nожидалось %#v", out, in)
+	}
+}
+
+func TestDecodeNullUnionBranch(t *testing.T) {
+	s := mustParse(t, `["null", "string"]`)
+	out, err := s.Decode([]byte{0})
+	if err != nil || out != nil {
+		t.Fatalf("Decode = %v, %v; ожидалось nil, nil", out, err)
+	}
+}
+
+func TestDecodeRejectsMalformed(t *testing.T) {
+	s := mustParse(t, `"string"`)
+	tests := map[string][]byte{
+		"пусто": nil,
+		"строка длиннее данных":   binary.AppendVarint(nil, 10),
+		"отрицательная длина":     binary.AppendVarint(nil, -1),
+		"длина около MaxInt64":    append(binary.AppendVarint(nil, math.MaxInt64-1), 'a'),
+		"длина MaxInt64":          binary.AppendVarint(nil, math.MaxInt64),
+		"незаконченный varint":    {0x80},
+		"лишние байты после него": {0, 1},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.Decode(data); err == nil {
+				t.Fatal("ожидалась ошибка")
+			}
+		})
+	}
+}
+
+func TestDecodeShortFixedWidth(t *testing.T) {
+	for _, typ := range []string{`"double"`, `"float"`, `"boolean"`} {
+		s := mustParse(t, typ)
+		if _, err := s.Decode(nil); !errors.Is(err, errShort) {
+			t.Errorf("%s: получено %v, ожидалось %v", typ, err, errShort)
+		}
+	}
+}
+
+func TestDecodeUnionOutOfRange(t *testing.T) {
+	s := mustParse(t, `["null", "string"]`)
+	if _, err := s.Decode(binary.AppendVarint(nil, 5)); err == nil {
+		t.Fatal("ожидалась ошибка для несуществующей ветки union")
+	}
+}
+
+// FuzzDecode проверяет, что произвольные данные не роняют декодер
+func FuzzDecode(f *testing.F) {
+	s := mustParse(f, testSchema)
+	valid, err := s.Encode(map[string]any{
+		"name": "a", "count": int64(1), "price": 1.0, "at": time.Unix(0, 0),
+		"note": nil, "tags": map[string]any{}, "items": []any{map[string]any{"id": "x", "qty": int32(1)}},
+	})
+	if err != nil {
+		f.Fatalf("Encode: %v", err)
+	}
+	f.Add(valid)
+	f.Add(binary.AppendVarint(nil, math.MaxInt64-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, err := s.Decode(data)
+		if err != nil {
+			return
+		}
+		// Успешно декодированное значение должно кодироваться обратно
+		if _, err := s.Encode(out); err != nil {
+			t.Fatalf("декодированное значение не кодируется: %v", err)
+		}
+	})
+}
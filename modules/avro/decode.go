@@ -0,0 +1,193 @@
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var errShort = errors.New("avro: данные обрываются")
+
+// Decode декодирует одно значение, занимающее весь data
+func (s *Schema) Decode(data []byte) (any, error) {
+	d := decoder{data: data}
+	v, err := d.value(s)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("avro: лишние %d байт после значения", len(d.data)-d.pos)
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) long() (int64, error) {
+	v, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errShort
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *decoder) bytes(n int) ([]byte, error) {
+	// n > len-pos, а не pos+n > len: сумма переполняется на длинах около MaxInt64
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) sized() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > int64(len(d.data)-d.pos) {
+		return nil, errShort
+	}
+	return d.bytes(int(n))
+}
+
+// blocks читает массив или map, закодированные блоками, вызывая item для каждого элемента
+func (d *decoder) blocks(item func() error) error {
+	for {
+		count, err := d.long()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			// Отрицательное количество — за ним идёт размер блока в байтах
+			count = -count
+			if _, err := d.long(); err != nil {
+				return err
+			}
+		}
+		for ; count > 0; count-- {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d *decoder) value(s *Schema) (any, error) {
+	switch s.Type {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.bytes(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		v, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		switch s.LogicalType {
+		case "timestamp-millis":
+			return time.UnixMilli(v).UTC(), nil
+		case "timestamp-micros":
+			return time.UnixMicro(v).UTC(), nil
+		case "date":
+			return time.Unix(v*24*60*60, 0).UTC(), nil
+		}
+		if s.Type == "int" {
+			return int32(v), nil
+		}
+		return v, nil
+	case "float":
+		b, err := d.bytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "double":
+		b, err := d.bytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes":
+		b, err := d.sized()
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case "string":
+		b, err := d.sized()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "fixed":
+		b, err := d.bytes(s.Size)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case "enum":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(s.Symbols) {
+			return nil, fmt.Errorf("avro: номер символа %d вне enum %s", i, s.Name)
+		}
+		return s.Symbols[i], nil
+	case "union":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(s.Branches) {
+			return nil, fmt.Errorf("avro: номер ветки %d вне union", i)
+		}
+		return d.value(s.Branches[i])
+	case "array":
+		out := []any{}
+		err := d.blocks(func() error {
+			v, err := d.value(s.Items)
+			out = append(out, v)
+			return err
+		})
+		return out, err
+	case "map":
+		out := map[string]any{}
+		err := d.blocks(func() error {
+			k, err := d.sized()
+			if err != nil {
+				return err
+			}
+			v, err := d.value(s.Values)
+			out[string(k)] = v
+			return err
+		})
+		return out, err
+	case "record":
+		out := make(map[string]any, len(s.Fields))
+		for _, f := range s.Fields {
+			v, err := d.value(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", s.Name, f.Name, err)
+			}
+			out[f.Name] = v
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("avro: неподдерживаемый тип %s", s.Type)
+}
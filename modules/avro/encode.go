@@ -0,0 +1,204 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Encode кодирует значение по схеме. Записи передаются как map[string]any,
+// числа — любым числовым типом Go или json.Number, время — time.Time
+// или строкой RFC 3339
+func (s *Schema) Encode(v any) ([]byte, error) {
+	var e encoder
+	if err := e.value(s, v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) long(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *encoder) sized(b []byte) {
+	e.long(int64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) value(s *Schema, v any) error {
+	switch s.Type {
+	case "null":
+		if v != nil {
+			return fmt.Errorf("avro: ожидался null, получено %T", v)
+		}
+		return nil
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("avro: ожидался boolean, получено %T", v)
+		}
+		if b {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+		return nil
+	case "int", "long":
+		n, err := integer(s.LogicalType, v)
+		if err != nil {
+			return err
+		}
+		e.long(n)
+		return nil
+	case "float":
+		f, ok := float(v)
+		if !ok {
+			return fmt.Errorf("avro: ожидалось число, получено %T", v)
+		}
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, math.Float32bits(float32(f)))
+		return nil
+	case "double":
+		f, ok := float(v)
+		if !ok {
+			return fmt.Errorf("avro: ожидалось число, получено %T", v)
+		}
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(f))
+		return nil
+	case "string", "bytes":
+		switch b := v.(type) {
+		case string:
+			e.sized([]byte(b))
+		case []byte:
+			e.sized(b)
+		default:
+			return fmt.Errorf("avro: ожидалась строка, получено %T", v)
+		}
+		return nil
+	case "fixed":
+		b, ok := v.([]byte)
+		if !ok || len(b) != s.Size {
+			return fmt.Errorf("avro: ожидалось %d байт для %s", s.Size, s.Name)
+		}
+		e.buf = append(e.buf, b...)
+		return nil
+	case "enum":
+		sym, _ := v.(string)
+		for i, candidate := range s.Symbols {
+			if candidate == sym {
+				e.long(int64(i))
+				return nil
+			}
+		}
+		return fmt.Errorf("avro: %v нет в enum %s", v, s.Name)
+	case "union":
+		// Берём первую ветку, которой подходит значение
+		for i, b := range s.Branches {
+			var try encoder
+			if try.value(b, v) == nil {
+				e.long(int64(i))
+				e.buf = append(e.buf, try.buf...)
+				return nil
+			}
+		}
+		return fmt.Errorf("avro: значение %T не подходит ни одной ветке union", v)
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("avro: ожидался массив, получено %T", v)
+		}
+		if len(items) > 0 {
+			e.long(int64(len(items)))
+			for _, item := range items {
+				if err := e.value(s.Items, item); err != nil {
+					return err
+				}
+			}
+		}
+		e.long(0)
+		return nil
+	case "map":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("avro: ожидался объект, получено %T", v)
+		}
+		if len(m) > 0 {
+			e.long(int64(len(m)))
+			for k, item := range m {
+				e.sized([]byte(k))
+				if err := e.value(s.Values, item); err != nil {
+					return err
+				}
+			}
+		}
+		e.long(0)
+		return nil
+	case "record":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("avro: ожидалась запись %s, получено %T", s.Name, v)
+		}
+		for _, f := range s.Fields {
+			if err := e.value(f.Type, m[f.Name]); err != nil {
+				return fmt.Errorf("%s.%s: %w", s.Name, f.Name, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("avro: неподдерживаемый тип %s", s.Type)
+}
+
+// integer приводит значение к int/long, учитывая логический тип времени
+func integer(logical string, v any) (int64, error) {
+	var t time.Time
+	switch x := v.(type) {
+	case time.Time:
+		t = x
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, x)
+		if err != nil {
+			return 0, fmt.Errorf("avro: ожидалось время, получено %q", x)
+		}
+		t = parsed
+	default:
+		f, ok := float(v)
+		if !ok || f != math.Trunc(f) {
+			return 0, fmt.Errorf("avro: ожидалось целое число, получено %v", v)
+		}
+		return int64(f), nil
+	}
+	switch logical {
+	case "timestamp-millis":
+		return t.UnixMilli(), nil
+	case "timestamp-micros":
+		return t.UnixMicro(), nil
+	case "date":
+		return t.Unix() / (24 * 60 * 60), nil
+	}
+	return 0, fmt.Errorf("avro: время в поле без логического типа")
+}
+
+func float(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
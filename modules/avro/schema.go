@@ -0,0 +1,150 @@
+// Package avro — минимальная реализация бинарного кодирования Apache Avro:
+// разбор схемы (.avsc) и кодирование/декодирование значений по ней.
+// Записи декодируются в map[string]any, массивы — в []any,
+// long с логическим типом timestamp-millis/timestamp-micros — в time.Time
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Schema — разобранная схема Avro
+type Schema struct {
+	Type        string // null, boolean, int, long, float, double, bytes, string, record, enum, array, map, fixed, union
+	Name        string
+	LogicalType string
+	Fields      []Field   // record
+	Symbols     []string  // enum
+	Items       *Schema   // array
+	Values      *Schema   // map
+	Size        int       // fixed
+	Branches    []*Schema // union
+}
+
+// Field — поле записи
+type Field struct {
+	Name string
+	Type *Schema
+}
+
+// Parse разбирает схему Avro в JSON-представлении
+func Parse(schemaJSON string) (*Schema, error) {
+	var raw any
+	if err := json.Unmarshal([]byte(schemaJSON), &raw); err != nil {
+		return nil, fmt.Errorf("avro: неверная схема: %w", err)
+	}
+	p := parser{names: make(map[string]*Schema)}
+	return p.parse(raw, "")
+}
+
+type parser struct {
+	names map[string]*Schema // именованные типы (record, enum, fixed) по полному имени
+}
+
+var primitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+func (p *parser) parse(raw any, namespace string) (*Schema, error) {
+	switch v := raw.(type) {
+	case string:
+		if primitives[v] {
+			return &Schema{Type: v}, nil
+		}
+		if s, ok := p.names[fullName(v, namespace)]; ok {
+			return s, nil
+		}
+		if s, ok := p.names[v]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("avro: неизвестный тип %q", v)
+	case []any:
+		u := &Schema{Type: "union"}
+		for _, b := range v {
+			s, err := p.parse(b, namespace)
+			if err != nil {
+				return nil, err
+			}
+			u.Branches = append(u.Branches, s)
+		}
+		return u, nil
+	case map[string]any:
+		return p.parseComplex(v, namespace)
+	}
+	return nil, fmt.Errorf("avro: неверное описание типа %v", raw)
+}
+
+func (p *parser) parseComplex(v map[string]any, namespace string) (*Schema, error) {
+	typ, _ := v["type"].(string)
+	logical, _ := v["logicalType"].(string)
+	if ns, ok := v["namespace"].(string); ok {
+		namespace = ns
+	}
+	name, _ := v["name"].(string)
+
+	switch typ {
+	case "record", "error":
+		s := &Schema{Type: "record", Name: name}
+		p.names[fullName(name, namespace)] = s
+		fields, _ := v["fields"].([]any)
+		for _, f := range fields {
+			fm, ok := f.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("avro: неверное поле записи %s", name)
+			}
+			fname, _ := fm["name"].(string)
+			ft, err := p.parse(fm["type"], namespace)
+			if err != nil {
+				return nil, fmt.Errorf("avro: поле %s.%s: %w", name, fname, err)
+			}
+			s.Fields = append(s.Fields, Field{Name: fname, Type: ft})
+		}
+		return s, nil
+	case "enum":
+		s := &Schema{Type: "enum", Name: name}
+		symbols, _ := v["symbols"].([]any)
+		for _, sym := range symbols {
+			str, _ := sym.(string)
+			s.Symbols = append(s.Symbols, str)
+		}
+		p.names[fullName(name, namespace)] = s
+		return s, nil
+	case "fixed":
+		size, _ := v["size"].(float64)
+		s := &Schema{Type: "fixed", Name: name, Size: int(size), LogicalType: logical}
+		p.names[fullName(name, namespace)] = s
+		return s, nil
+	case "array":
+		items, err := p.parse(v["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case "map":
+		values, err := p.parse(v["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "map", Values: values}, nil
+	default:
+		if primitives[typ] {
+			return &Schema{Type: typ, LogicalType: logical}, nil
+		}
+		// {"type": "SomeRecord"} — ссылка на именованный тип
+		return p.parse(typ, namespace)
+	}
+}
+
+func fullName(name, namespace string) string {
+	for _, c := range name {
+		if c == '.' {
+			return name
+		}
+	}
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
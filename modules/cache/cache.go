@@ -0,0 +1,432 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"project_wb_l0/modules/general"
+	"sync"
+	"time"
+)
+
+// Cache представляет собой in-memory кэш заказов с подключаемой политикой вытеснения
+// и временем жизни записей.
+// Данные разбиты на независимые сегменты (shard) по хэшу UID, чтобы чтения и записи
+// разных заказов не конкурировали за одну блокировку
+type Cache struct {
+	shards   []*shard
+	orders   OrderStore
+	loads    *flightGroup
+	negative *negativeCache
+	stats    counters
+
+	remote    RemoteTier // nil — второго уровня нет
+	remoteTTL time.Duration
+
+	ttl             time.Duration
+	sliding         bool
+	janitorInterval time.Duration
+	writeThrough    bool
+	restoreWorkers  int
+	restoreBatch    int
+
+	// Блокировки записей таблицы Hash по хэшу UID (см. syncHash)
+	hashLocks [hashStripes]sync.Mutex
+}
+
+// hashStripes — сколько блокировок делят между собой UID'ы таблицы Hash
+const hashStripes = 256
+
+// Option настраивает кэш при создании
+type Option func(*cacheOptions)
+
+type cacheOptions struct {
+	policy          string
+	shards          int
+	maxBytes        int64
+	ttl             time.Duration
+	sliding         bool
+	janitorInterval time.Duration
+	negativeTTL     time.Duration
+	negativeMax     int
+	writeThrough    bool
+	restoreWorkers  int
+	restoreBatch    int
+	remote          RemoteTier
+	remoteTTL       time.Duration
+}
+
+// WithPolicy задаёт политику вытеснения по названию (random, lru, lfu, arc)
+func WithPolicy(name string) Option {
+	return func(o *cacheOptions) {
+		o.policy = name
+	}
+}
+
+// WithShards задаёт количество независимых сегментов кэша.
+// Ёмкость maxItems делится между сегментами поровну
+func WithShards(n int) Option {
+	return func(o *cacheOptions) {
+		o.shards = n
+	}
+}
+
+// WithMaxBytes переключает кэш на ограничение по памяти: каждый заказ
+// оценивается по занимаемому размеру, и суммарный объём держится в пределах maxBytes.
+// Количество записей при этом не ограничивается (0 — ограничение по количеству)
+func WithMaxBytes(maxBytes int64) Option {
+	return func(o *cacheOptions) {
+		o.maxBytes = maxBytes
+	}
+}
+
+// WithTTL задаёт время жизни записей по умолчанию (0 — без ограничения)
+func WithTTL(ttl time.Duration) Option {
+	return func(o *cacheOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithSlidingExpiration включает продление жизни записи при каждом чтении
+func WithSlidingExpiration(enabled bool) Option {
+	return func(o *cacheOptions) {
+		o.sliding = enabled
+	}
+}
+
+// WithJanitorInterval задаёт, как часто фоновая горутина удаляет истёкшие записи
+func WithJanitorInterval(interval time.Duration) Option {
+	return func(o *cacheOptions) {
+		o.janitorInterval = interval
+	}
+}
+
+// WithNegativeCaching включает запоминание отсутствующих в БД UID'ов
+// на время ttl, но не более maxItems штук (ttl = 0 — выключено)
+func WithNegativeCaching(ttl time.Duration, maxItems int) Option {
+	return func(o *cacheOptions) {
+		o.negativeTTL = ttl
+		o.negativeMax = maxItems
+	}
+}
+
+// WithWriteThrough включает запись в кэш заказов, пришедших из Kafka:
+// новый заказ попадает в кэш сразу, а уже закэшированный обновляется
+func WithWriteThrough(enabled bool) Option {
+	return func(o *cacheOptions) {
+		o.writeThrough = enabled
+	}
+}
+
+// WithRestore задаёт, сколькими горутинами и какими пачками UID'ов
+// RestoreFromDB загружает заказы из БД
+func WithRestore(workers, batchSize int) Option {
+	return func(o *cacheOptions) {
+		o.restoreWorkers = workers
+		o.restoreBatch = batchSize
+	}
+}
+
+// WithRemoteTier подключает второй уровень кэша (например, Redis), общий для экземпляров
+// сервиса. Заказы хранятся в нём ttl (0 — без ограничения)
+func WithRemoteTier(remote RemoteTier, ttl time.Duration) Option {
+	return func(o *cacheOptions) {
+		o.remote = remote
+		o.remoteTTL = ttl
+	}
+}
+
+// NewCache создаёт новый кэш с заданным максимальным количеством записей.
+// По умолчанию используется политика LRU и один сегмент
+func NewCache(maxItems int, orders OrderStore, opts ...Option) *Cache {
+	o := cacheOptions{
+		policy:          PolicyLRU,
+		shards:          1,
+		janitorInterval: time.Minute,
+		restoreWorkers:  4,
+		restoreBatch:    100,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if _, err := NewPolicy(o.policy, maxItems); err != nil {
+		log.Printf("%v. Используется политика по умолчанию: %s", err, PolicyLRU)
+		o.policy = PolicyLRU
+	}
+	if o.maxBytes <= 0 {
+		// В каждом сегменте должно помещаться хотя бы по одной записи
+		o.shards = min(o.shards, maxItems)
+	}
+	o.shards = max(1, o.shards)
+
+	shards := make([]*shard, o.shards)
+	for i := range shards {
+		// Первые maxItems % n сегментов получают на одну запись больше,
+		// чтобы суммарная ёмкость была ровно maxItems
+		capacity := maxItems / o.shards
+		if i < maxItems%o.shards {
+			capacity++
+		}
+		policy, _ := NewPolicy(o.policy, capacity)
+		shards[i] = newShard(capacity, o.maxBytes/int64(o.shards), policy)
+	}
+
+	return &Cache{
+		shards:          shards,
+		orders:          orders,
+		loads:           newFlightGroup(),
+		negative:        newNegativeCache(o.negativeTTL, o.negativeMax),
+		ttl:             o.ttl,
+		sliding:         o.sliding,
+		janitorInterval: o.janitorInterval,
+		writeThrough:    o.writeThrough,
+		restoreWorkers:  max(1, o.restoreWorkers),
+		restoreBatch:    max(1, o.restoreBatch),
+		remote:          o.remote,
+		remoteTTL:       o.remoteTTL,
+	}
+}
+
+// shardFor возвращает сегмент, в котором хранится uid
+func (c *Cache) shardFor(uid string) *shard {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	return c.shards[hashUID(uid)%uint32(len(c.shards))]
+}
+
+// hashUID — хэш FNV-1a от uid. В отличие от hash/fnv не выделяет память на каждый вызов
+func hashUID(uid string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(uid); i++ {
+		h ^= uint32(uid[i])
+		h *= 16777619
+	}
+	return h
+}
+
+// Start запускает фоновую очистку истёкших записей.
+// Горутина завершается вместе с ctx
+func (c *Cache) Start(ctx context.Context) {
+	if c.janitorInterval <= 0 {
+		return
+	}
+	go c.janitor(ctx)
+}
+
+// janitor периодически удаляет истёкшие записи из кэша и таблицы Hash
+func (c *Cache) janitor(ctx context.Context) {
+	clock := time.NewTicker(c.janitorInterval)
+	defer clock.Stop()
+	for {
+		select {
+		case <-clock.C:
+			if n := c.deleteExpired(); n > 0 {
+				log.Printf("Удалено истёкших записей из кэша: %d", n)
+			}
+			c.negative.deleteExpired()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deleteExpired удаляет все истёкшие записи и возвращает их количество
+func (c *Cache) deleteExpired() int {
+	removed := 0
+	for _, sh := range c.shards {
+		uids := sh.deleteExpired()
+		c.syncHash(uids...)
+		removed += len(uids)
+	}
+	c.stats.expirations.Add(int64(removed))
+	return removed
+}
+
+// Get — получает заказ из кэша или БД.
+// Одновременные промахи по одному UID выполняют один общий запрос в БД,
+// при этом каждый вызывающий может прекратить ожидание через свой ctx
+func (c *Cache) Get(ctx context.Context, uid string) (general.Order, error) {
+	order, _, err := c.Fetch(ctx, uid)
+	return order, err
+}
+
+// Fetch работает как Get и дополнительно сообщает, найден ли заказ в памяти (hit)
+func (c *Cache) Fetch(ctx context.Context, uid string) (order general.Order, hit bool, err error) {
+	order, ok := c.lookup(uid)
+	log.Println("Ищем в кэше")
+	if ok {
+		c.stats.hits.Add(1)
+		return order, true, nil
+	}
+	c.stats.misses.Add(1)
+	if c.negative.contains(uid) {
+		c.stats.negativeHits.Add(1)
+		return general.Order{}, false, fmt.Errorf("заказ %s: %w", uid, general.ErrOrderNotFound)
+	}
+	log.Println("Не нашли в кэш, ищем в бд")
+	order, err = c.loads.do(ctx, uid, func() (general.Order, error) {
+		return c.load(uid)
+	})
+	return order, false, err
+}
+
+// load загружает заказ из второго уровня кэша или из БД и сохраняет его в память
+func (c *Cache) load(uid string) (general.Order, error) {
+	// Пока ждали своей очереди, заказ мог загрузить предыдущий запрос
+	order, ok := c.lookup(uid)
+	if ok {
+		return order, nil
+	}
+
+	// Второй уровень: заказ мог загрузить другой экземпляр сервиса
+	if order, ok := c.remoteGet(uid); ok {
+		c.store(uid, order, c.ttl, false)
+		return order, nil
+	}
+
+	// Если нет в кэше — загружаем из БД.
+	// Поколение берём до запроса: если заказ запишут, пока мы его ищем, «не найден» не запомнится
+	gen := c.negative.generation(uid)
+	var dbOrder general.Order
+	start := time.Now()
+	err := c.orders.GetOrderByUID(uid, &dbOrder)
+	c.stats.loads.Add(1)
+	c.stats.loadNanos.Add(int64(time.Since(start)))
+	if err != nil {
+		if !errors.Is(err, general.ErrOrderNotFound) {
+			c.stats.loadErrors.Add(1)
+		}
+		if errors.Is(err, general.ErrOrderNotFound) {
+			c.negative.add(uid, gen)
+		}
+		var empty general.Order
+		return empty, err
+	}
+	log.Println("Сохраняем в кэш")
+	// Сохраняем в кэш, не затирая версию, которую успел положить write-through
+	c.store(uid, dbOrder, c.ttl, false)
+	c.remoteSet(uid, dbOrder)
+	return dbOrder, nil
+}
+
+// lookup ищет живую запись в кэше. Истёкшая запись удаляется
+// вместе с UID'ом в таблице Hash
+func (c *Cache) lookup(uid string) (general.Order, bool) {
+	order, ok, expired := c.shardFor(uid).lookup(uid, c.sliding)
+	if expired {
+		c.stats.expirations.Add(1)
+		c.syncHash(uid)
+	}
+	return order, ok
+}
+
+// Set — добавляет заказ в кэш и в таблицу  Hash из бд
+// со временем жизни по умолчанию
+func (c *Cache) Set(uid string, order general.Order) {
+	c.SetWithTTL(uid, order, c.ttl)
+}
+
+// SetWithTTL — добавляет заказ в кэш с собственным временем жизни (0 — без ограничения)
+func (c *Cache) SetWithTTL(uid string, order general.Order, ttl time.Duration) {
+	c.store(uid, order, ttl, true)
+}
+
+// store кладёт заказ в кэш и сохраняет новый UID в таблицу Hash.
+// При overwrite = false живая запись с тем же UID остаётся нетронутой.
+// Обращения к БД выполняются уже после снятия блокировки сегмента
+func (c *Cache) store(uid string, order general.Order, ttl time.Duration, overwrite bool) {
+	added, evicted := c.shardFor(uid).store(uid, order, ttl, overwrite)
+	c.dropEvicted(evicted)
+	if !added {
+		return
+	}
+	c.syncHash(uid)
+}
+
+// OrderWritten сообщает кэшу, что заказ записан в БД из Kafka:
+// UID больше не считается отсутствующим, а в режиме write-through
+// заказ добавляется в кэш или обновляется в нём. Без write-through
+// обновляется только уже закэшированная запись: собственное уведомление
+// об изменении этот экземпляр пропускает. Во втором уровне кэша
+// заказ обновляется (write-through) или удаляется, чтобы не отдавать старую версию
+func (c *Cache) OrderWritten(order general.Order) {
+	c.negative.forget(order.OrderUID)
+	if c.writeThrough {
+		c.Set(order.OrderUID, order)
+		c.remoteSet(order.OrderUID, order)
+		return
+	}
+	if c.contains(order.OrderUID) {
+		c.Set(order.OrderUID, order)
+	}
+	c.remoteDelete(order.OrderUID)
+}
+
+// dropEvicted учитывает вытесненные записи и удаляет их из таблицы Hash
+func (c *Cache) dropEvicted(uids []string) {
+	c.stats.evictions.Add(int64(len(uids)))
+	c.syncHash(uids...)
+}
+
+// syncHash приводит таблицу Hash в БД к содержимому кэша: UID добавляется, если он
+// сейчас в кэше, и удаляется, если нет. Запросы к БД идут после снятия блокировки
+// сегмента, поэтому добавление и вытеснение одного UID могут дойти до БД в обратном порядке.
+// Проверка и запрос выполняются под блокировкой UID, и последний из них
+// всегда видит итоговое состояние сегмента
+func (c *Cache) syncHash(uids ...string) {
+	for _, uid := range uids {
+		mu := &c.hashLocks[hashUID(uid)%hashStripes]
+		mu.Lock()
+		if c.contains(uid) {
+			if err := c.orders.SaveOrderToCacheBd(uid); err != nil {
+				log.Println("Ошибка сохранения ", uid, "в HASH: ", err)
+			}
+		} else if err := c.orders.RemoveFromHash(uid); err != nil {
+			log.Printf("Не удалось удалить запись из Hash: %v", err)
+		}
+		mu.Unlock()
+	}
+}
+
+// remoteGet ищет заказ во втором уровне кэша. Ошибки L2 не мешают
+// обращению к БД и только учитываются в статистике
+func (c *Cache) remoteGet(uid string) (general.Order, bool) {
+	if c.remote == nil {
+		return general.Order{}, false
+	}
+	order, ok, err := c.remote.Get(context.Background(), uid)
+	switch {
+	case err != nil:
+		c.stats.remoteErrors.Add(1)
+		log.Printf("Ошибка второго уровня кэша: %v", err)
+	case ok:
+		c.stats.remoteHits.Add(1)
+	default:
+		c.stats.remoteMisses.Add(1)
+	}
+	return order, ok && err == nil
+}
+
+// remoteSet сохраняет заказ во втором уровне кэша
+func (c *Cache) remoteSet(uid string, order general.Order) {
+	if c.remote == nil {
+		return
+	}
+	if err := c.remote.Set(context.Background(), uid, order, c.remoteTTL); err != nil {
+		c.stats.remoteErrors.Add(1)
+		log.Printf("Ошибка второго уровня кэша: %v", err)
+	}
+}
+
+// remoteDelete удаляет заказ из второго уровня кэша
+func (c *Cache) remoteDelete(uid string) {
+	if c.remote == nil {
+		return
+	}
+	if err := c.remote.Delete(context.Background(), uid); err != nil {
+		c.stats.remoteErrors.Add(1)
+		log.Printf("Ошибка второго уровня кэша: %v", err)
+	}
+}
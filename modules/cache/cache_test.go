@@ -0,0 +1,297 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/general"
+)
+
+func TestMain(m *testing.M) {
+	// Кэш пишет в лог на каждое обращение
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestCache создаёт кэш поверх хранилища в памяти с заказами uids
+func newTestCache(maxItems int, uids []string, opts ...Option) (*Cache, *MemoryStore) {
+	store := NewMemoryStore()
+	for _, uid := range uids {
+		store.PutOrder(general.Order{OrderUID: uid, TrackNumber: "track-" + uid})
+	}
+	return NewCache(maxItems, store, opts...), store
+}
+
+func TestGetLoadsFromStoreOnce(t *testing.T) {
+	c, store := newTestCache(10, []string{"a"})
+	ctx := context.Background()
+
+	order, hit, err := c.Fetch(ctx, "a")
+	if err != nil || hit || order.TrackNumber != "track-a" {
+		t.Fatalf("первое чтение = %+v, hit=%v, %v; ожидалась загрузка из хранилища", order, hit, err)
+	}
+	order, hit, err = c.Fetch(ctx, "a")
+	if err != nil || !hit || order.TrackNumber != "track-a" {
+		t.Fatalf("второе чтение = %+v, hit=%v, %v; ожидалось попадание", order, hit, err)
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Loads != 1 {
+		t.Fatalf("статистика %+v", s)
+	}
+	if uids, _ := store.GetAllHashUIDs(); len(uids) != 1 || uids[0] != "a" {
+		t.Fatalf("Hash = %v, ожидалось [a]", uids)
+	}
+}
+
+func TestGetMissing(t *testing.T) {
+	c, _ := newTestCache(10, nil, WithNegativeCaching(time.Hour, 10))
+	ctx := context.Background()
+	for range 2 {
+		if _, err := c.Get(ctx, "nope"); !errors.Is(err, general.ErrOrderNotFound) {
+			t.Fatalf("ожидалась ErrOrderNotFound, получено %v", err)
+		}
+	}
+	if s := c.Stats(); s.Loads != 1 || s.NegativeHits != 1 {
+		t.Fatalf("второй запрос должен ответить из негативного кэша: %+v", s)
+	}
+}
+
+func TestSetOverwrites(t *testing.T) {
+	c, _ := newTestCache(10, []string{"a"})
+	c.Set("a", general.Order{OrderUID: "a", TrackNumber: "v1"})
+	c.Set("a", general.Order{OrderUID: "a", TrackNumber: "v2"})
+	order, hit, err := c.Fetch(context.Background(), "a")
+	if err != nil || !hit || order.TrackNumber != "v2" {
+		t.Fatalf("Fetch = %+v, hit=%v, %v; ожидалась версия v2 из кэша", order, hit, err)
+	}
+	if n := c.Stats().Items; n != 1 {
+		t.Fatalf("записей %d, ожидалась 1", n)
+	}
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	// В кэше на две записи a читается, b нет — при добавлении c вытесняется b
+	tests := []struct {
+		policy  string
+		victims []string // допустимые жертвы
+	}{
+		{PolicyLRU, []string{"b"}},
+		{PolicyLFU, []string{"b"}},
+		{PolicyARC, []string{"b"}},
+		{PolicyRandom, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			uids := []string{"a", "b", "c"}
+			c, store := newTestCache(2, uids, WithPolicy(tt.policy))
+			ctx := context.Background()
+			c.Set("a", general.Order{OrderUID: "a"})
+			c.Set("b", general.Order{OrderUID: "b"})
+			for range 3 {
+				if _, hit, _ := c.Fetch(ctx, "a"); !hit {
+					t.Fatal("a не найден в кэше")
+				}
+			}
+			c.Set("c", general.Order{OrderUID: "c"})
+
+			keys := c.Keys()
+			if len(keys) != 2 || !contains(keys, "c") {
+				t.Fatalf("в кэше %v, ожидались две записи, включая c", keys)
+			}
+			var victim string
+			for _, uid := range []string{"a", "b"} {
+				if !contains(keys, uid) {
+					victim = uid
+				}
+			}
+			if !contains(tt.victims, victim) {
+				t.Fatalf("вытеснен %q, ожидался один из %v", victim, tt.victims)
+			}
+			if n := c.Stats().Evictions; n != 1 {
+				t.Fatalf("вытеснений %d, ожидалось 1", n)
+			}
+			if hash, _ := store.GetAllHashUIDs(); contains(hash, victim) {
+				t.Fatalf("вытесненный %s остался в Hash: %v", victim, hash)
+			}
+		})
+	}
+}
+
+func TestEvictionByBytes(t *testing.T) {
+	order := general.Order{OrderUID: "a", TrackNumber: "track"}
+	size := orderSize("a", order)
+	c, _ := newTestCache(0, []string{"a", "b"}, WithMaxBytes(size*3/2))
+	c.Set("a", order)
+	c.Set("b", general.Order{OrderUID: "b", TrackNumber: "track"})
+	if keys := c.Keys(); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("в кэше %v, ожидался только b", keys)
+	}
+}
+
+func TestTTLExpiry(t *testing.T) {
+	c, store := newTestCache(10, []string{"a", "b"})
+	c.SetWithTTL("a", general.Order{OrderUID: "a", TrackNumber: "cached"}, 20*time.Millisecond)
+	c.SetWithTTL("b", general.Order{OrderUID: "b", TrackNumber: "cached"}, time.Hour)
+	time.Sleep(40 * time.Millisecond)
+
+	order, hit, err := c.Fetch(context.Background(), "a")
+	if err != nil || hit || order.TrackNumber != "track-a" {
+		t.Fatalf("истёкшая запись: %+v, hit=%v, %v; ожидалась загрузка из хранилища", order, hit, err)
+	}
+	if _, hit, _ := c.Fetch(context.Background(), "b"); !hit {
+		t.Fatal("живая запись b пропала")
+	}
+	if n := c.Stats().Expirations; n != 1 {
+		t.Fatalf("истечений %d, ожидалось 1", n)
+	}
+
+	// Фоновая очистка удаляет истёкшие записи и из Hash
+	c.SetWithTTL("a", general.Order{OrderUID: "a"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if n := c.deleteExpired(); n != 1 {
+		t.Fatalf("deleteExpired = %d, ожидалось 1", n)
+	}
+	if hash, _ := store.GetAllHashUIDs(); contains(hash, "a") {
+		t.Fatalf("истёкший a остался в Hash: %v", hash)
+	}
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	c, _ := newTestCache(10, []string{"a"}, WithTTL(60*time.Millisecond), WithSlidingExpiration(true))
+	c.Set("a", general.Order{OrderUID: "a"})
+	for range 4 {
+		time.Sleep(30 * time.Millisecond)
+		if _, hit, _ := c.Fetch(context.Background(), "a"); !hit {
+			t.Fatal("чтение не продлило жизнь записи")
+		}
+	}
+}
+
+func TestRestoreFromDB(t *testing.T) {
+	uids := []string{"a", "b", "c", "d", "e"}
+	store := NewMemoryStore()
+	for _, uid := range uids {
+		store.PutOrder(general.Order{OrderUID: uid, TrackNumber: "track-" + uid})
+		if err := store.SaveOrderToCacheBd(uid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Заказ e удалили из БД, а в Hash он остался
+	delete(store.orders, "e")
+
+	c := NewCache(10, store, WithRestore(3, 2))
+	summary, err := c.RestoreFromDB(context.Background())
+	if err != nil {
+		t.Fatalf("RestoreFromDB: %v", err)
+	}
+	if summary.Total != 5 || summary.Loaded != 4 || summary.Missing != 1 || summary.Failed != 0 {
+		t.Fatalf("итог восстановления %+v", summary)
+	}
+	for _, uid := range uids[:4] {
+		order, hit, err := c.Fetch(context.Background(), uid)
+		if err != nil || !hit || order.TrackNumber != "track-"+uid {
+			t.Fatalf("%s после восстановления: %+v, hit=%v, %v", uid, order, hit, err)
+		}
+	}
+	if hash, _ := store.GetAllHashUIDs(); len(hash) != 4 || contains(hash, "e") {
+		t.Fatalf("Hash = %v, e должен быть удалён", hash)
+	}
+}
+
+func TestRestoreFromDBCancelled(t *testing.T) {
+	store := NewMemoryStore()
+	for _, uid := range []string{"a", "b", "c"} {
+		store.PutOrder(general.Order{OrderUID: uid})
+		store.SaveOrderToCacheBd(uid)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewCache(10, store).RestoreFromDB(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ожидалась отмена, получено %v", err)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOrderWrittenRefreshesCachedOrder(t *testing.T) {
+	c, store := newTestCache(10, []string{"a"})
+	ctx := context.Background()
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Новая версия записана этим экземпляром: своё уведомление он не получит
+	fresh := general.Order{OrderUID: "a", TrackNumber: "v2"}
+	store.PutOrder(fresh)
+	c.OrderWritten(fresh)
+
+	order, hit, err := c.Fetch(ctx, "a")
+	if err != nil || !hit || order.TrackNumber != "v2" {
+		t.Fatalf("после записи: %+v, hit=%v, %v; ожидалась версия v2 из кэша", order, hit, err)
+	}
+
+	// Незакэшированный заказ без write-through в кэш не добавляется
+	c.OrderWritten(general.Order{OrderUID: "b"})
+	if contains(c.Keys(), "b") {
+		t.Fatal("заказ b добавлен в кэш без write-through")
+	}
+}
+
+// hashGateStore задерживает запись uid в Hash, пока тест не разрешит её
+type hashGateStore struct {
+	*MemoryStore
+	uid     string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *hashGateStore) SaveOrderToCacheBd(uid string) error {
+	if uid == s.uid {
+		close(s.started)
+		<-s.release
+	}
+	return s.MemoryStore.SaveOrderToCacheBd(uid)
+}
+
+// Запись в Hash, опоздавшая к вытеснению, не должна оставить в Hash вытесненный UID
+func TestHashFollowsEvictionRacingSave(t *testing.T) {
+	store := &hashGateStore{MemoryStore: NewMemoryStore(), uid: "a", started: make(chan struct{}), release: make(chan struct{})}
+	for _, uid := range []string{"a", "b"} {
+		store.PutOrder(general.Order{OrderUID: uid})
+	}
+	c := NewCache(1, store)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		c.Set("a", general.Order{OrderUID: "a"})
+	}()
+	<-store.started
+	go func() {
+		defer wg.Done()
+		c.Set("b", general.Order{OrderUID: "b"})
+	}()
+	for c.contains("a") {
+		runtime.Gosched()
+	}
+	close(store.release)
+	wg.Wait()
+
+	if hash, _ := store.GetAllHashUIDs(); len(hash) != 1 || hash[0] != "b" {
+		t.Fatalf("Hash = %v, ожидалось [b]", hash)
+	}
+}
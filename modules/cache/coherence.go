@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"errors"
+	"log"
+	"project_wb_l0/modules/general"
+)
+
+// OrderUpdated обрабатывает сообщение о том, что заказ uid перезаписан
+// другим экземпляром сервиса: UID больше не считается отсутствующим,
+// а закэшированная версия перечитывается из БД
+func (c *Cache) OrderUpdated(uid string) {
+	c.negative.forget(uid)
+	if !c.contains(uid) {
+		return
+	}
+
+	var order general.Order
+	err := c.orders.GetOrderByUID(uid, &order)
+	if err != nil {
+		if !errors.Is(err, general.ErrOrderNotFound) {
+			log.Printf("Не удалось обновить заказ %s в кэше: %v", uid, err)
+		}
+		// Лучше промах, чем устаревшие данные
+		c.Delete(uid)
+		return
+	}
+	c.Set(uid, order)
+	log.Printf("Заказ %s обновлён в кэше по уведомлению", uid)
+}
+
+// UpdatesMissed перечитывает из БД все закэшированные заказы:
+// пока слушатель уведомлений был отключён, часть изменений могла пройти мимо
+func (c *Cache) UpdatesMissed() {
+	c.negative.clear()
+	refreshed, total := c.refreshAll()
+	log.Printf("Кэш перечитан после пропуска уведомлений: обновлено %d из %d", refreshed, total)
+}
+
+// refreshAll перечитывает из БД все закэшированные заказы. Заказы, которых нет в БД
+// или которые не удалось прочитать, удаляются из кэша: лучше промах, чем устаревшие данные.
+// Возвращает количество обновлённых заказов и сколько их было в кэше
+func (c *Cache) refreshAll() (refreshed, total int) {
+	keys := c.Keys()
+	for i := 0; i < len(keys); i += c.restoreBatch {
+		batch := keys[i:min(i+c.restoreBatch, len(keys))]
+		orders, err := c.orders.GetOrdersByUIDs(batch)
+		for _, uid := range batch {
+			order, ok := orders[uid]
+			if err != nil || !ok {
+				c.Delete(uid)
+				continue
+			}
+			c.Set(uid, order)
+			refreshed++
+		}
+	}
+	return refreshed, len(keys)
+}
+
+// contains сообщает, есть ли uid в кэше (без учёта срока жизни и статистики)
+func (c *Cache) contains(uid string) bool {
+	sh := c.shardFor(uid)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	_, ok := sh.data[uid]
+	return ok
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"project_wb_l0/modules/general"
+	"sync"
+)
+
+// call — загрузка одного заказа, результат которой получают все ожидающие
+type call struct {
+	done  chan struct{}
+	order general.Order
+	err   error
+}
+
+// flightGroup схлопывает одновременные загрузки одного и того же UID в одну.
+// Загрузка выполняется в отдельной горутине и не зависит от контекстов
+// вызывающих: ушедший по отмене клиент не прерывает её для остальных
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*call)}
+}
+
+// do запускает load для uid, если такая загрузка ещё не идёт, и ждёт её результата
+// либо отмены ctx
+func (g *flightGroup) do(ctx context.Context, uid string, load func() (general.Order, error)) (general.Order, error) {
+	g.mu.Lock()
+	cl, ok := g.calls[uid]
+	if !ok {
+		cl = &call{done: make(chan struct{})}
+		g.calls[uid] = cl
+		go g.run(uid, cl, load)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-cl.done:
+		return cl.order, cl.err
+	case <-ctx.Done():
+		return general.Order{}, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(uid string, cl *call, load func() (general.Order, error)) {
+	cl.order, cl.err = load()
+
+	g.mu.Lock()
+	delete(g.calls, uid)
+	g.mu.Unlock()
+	close(cl.done)
+}
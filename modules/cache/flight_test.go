@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/general"
+)
+
+// waitMisses ждёт, пока n запросов промахнутся мимо памяти и встанут в очередь за загрузкой
+func waitMisses(t *testing.T, c *Cache, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Stats().Misses < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("промахов %d, ожидалось %d", c.Stats().Misses, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// От промаха до входа в flightGroup — несколько инструкций
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestConcurrentGetsLoadOnce(t *testing.T) {
+	const callers = 20
+	store := &slowStore{MemoryStore: NewMemoryStore(), started: make(chan struct{}), release: make(chan struct{})}
+	store.PutOrder(general.Order{OrderUID: "a", TrackNumber: "track-a"})
+	c := NewCache(10, store)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, err := c.Get(context.Background(), "a")
+			if err == nil && order.TrackNumber != "track-a" {
+				err = errors.New("получен не тот заказ")
+			}
+			errs <- err
+		}()
+	}
+	<-store.started
+	waitMisses(t, c, callers)
+	close(store.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Повторный вызов GetOrderByUID закрыл бы started второй раз и уронил тест
+	if n := c.Stats().Loads; n != 1 {
+		t.Fatalf("загрузок из БД %d, ожидалась 1", n)
+	}
+}
+
+func TestCancelledGetDoesNotAbortLoad(t *testing.T) {
+	store := &slowStore{MemoryStore: NewMemoryStore(), started: make(chan struct{}), release: make(chan struct{})}
+	store.PutOrder(general.Order{OrderUID: "a", TrackNumber: "track-a"})
+	c := NewCache(10, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		_, err := c.Get(ctx, "a")
+		cancelled <- err
+	}()
+	<-store.started
+
+	waiting := make(chan error)
+	go func() {
+		order, err := c.Get(context.Background(), "a")
+		if err == nil && order.TrackNumber != "track-a" {
+			err = errors.New("получен не тот заказ")
+		}
+		waiting <- err
+	}()
+	waitMisses(t, c, 2)
+
+	// Первый клиент ушёл, не дождавшись загрузки, которую сам и начал
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("отменённый запрос: %v, ожидалась context.Canceled", err)
+	}
+
+	close(store.release)
+	if err := <-waiting; err != nil {
+		t.Fatalf("оставшийся запрос: %v", err)
+	}
+	if _, hit, _ := c.Fetch(context.Background(), "a"); !hit {
+		t.Fatal("загруженный заказ не попал в кэш")
+	}
+	if n := c.Stats().Loads; n != 1 {
+		t.Fatalf("загрузок из БД %d, ожидалась 1", n)
+	}
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// generationStripes — сколько счётчиков поколений делят между собой все UID'ы
+const generationStripes = 1024
+
+// negativeCache помнит UID'ы, которых нет в БД, чтобы повторные запросы
+// несуществующих заказов не доходили до Postgres.
+// Размер ограничен: при переполнении удаляется самая старая запись.
+//
+// У каждого UID есть поколение, которое растёт при каждой записи заказа (forget).
+// Загрузка запоминает поколение до запроса в БД и не добавляет UID, если за время
+// запроса заказ успели записать. Чтобы память не росла с числом заказов, UID'ы делят
+// generationStripes счётчиков по хэшу: совпадение лишь изредка не даёт запомнить UID
+type negativeCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	maxItems    int
+	order       *list.List // *negativeEntry, самые старые в конце
+	items       map[string]*list.Element
+	generations [generationStripes]uint64
+}
+
+type negativeEntry struct {
+	uid       string
+	expiresAt time.Time
+}
+
+func newNegativeCache(ttl time.Duration, maxItems int) *negativeCache {
+	return &negativeCache{
+		ttl:      ttl,
+		maxItems: maxItems,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// enabled сообщает, включено ли негативное кэширование
+func (n *negativeCache) enabled() bool {
+	return n.ttl > 0 && n.maxItems > 0
+}
+
+// generation возвращает текущее поколение uid. Его нужно взять до запроса в БД
+// и передать в add
+func (n *negativeCache) generation(uid string) uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.generations[generationStripe(uid)]
+}
+
+// add запоминает, что заказа uid нет в БД, если с поколения gen заказ не записывали
+func (n *negativeCache) add(uid string, gen uint64) {
+	if !n.enabled() {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.generations[generationStripe(uid)] != gen {
+		// Заказ записали, пока его искали в БД: ответ «нет» уже устарел
+		return
+	}
+
+	expiresAt := time.Now().Add(n.ttl)
+	if el, ok := n.items[uid]; ok {
+		el.Value.(*negativeEntry).expiresAt = expiresAt
+		n.order.MoveToFront(el)
+		return
+	}
+	for n.order.Len() >= n.maxItems {
+		n.removeElement(n.order.Back())
+	}
+	n.items[uid] = n.order.PushFront(&negativeEntry{uid: uid, expiresAt: expiresAt})
+}
+
+// contains сообщает, известно ли, что заказа uid нет в БД
+func (n *negativeCache) contains(uid string) bool {
+	if !n.enabled() {
+		return false
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	el, ok := n.items[uid]
+	if !ok {
+		return false
+	}
+	if time.Now().After(el.Value.(*negativeEntry).expiresAt) {
+		n.removeElement(el)
+		return false
+	}
+	return true
+}
+
+// forget удаляет uid из негативного кэша (например, когда заказ появился в БД)
+// и начинает новое поколение uid
+func (n *negativeCache) forget(uid string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.generations[generationStripe(uid)]++
+	if el, ok := n.items[uid]; ok {
+		n.removeElement(el)
+	}
+}
+
+// deleteExpired удаляет истёкшие записи и возвращает их количество
+func (n *negativeCache) deleteExpired() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for el := n.order.Back(); el != nil; {
+		prev := el.Prev()
+		if now.After(el.Value.(*negativeEntry).expiresAt) {
+			n.removeElement(el)
+			removed++
+		}
+		el = prev
+	}
+	return removed
+}
+
+// clear удаляет все записи и начинает новое поколение всех UID'ов
+func (n *negativeCache) clear() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for i := range n.generations {
+		n.generations[i]++
+	}
+	n.order.Init()
+	clear(n.items)
+}
+
+// generationStripe — номер счётчика поколения uid
+func generationStripe(uid string) int {
+	return int(hashUID(uid) % generationStripes)
+}
+
+func (n *negativeCache) removeElement(el *list.Element) {
+	n.order.Remove(el)
+	delete(n.items, el.Value.(*negativeEntry).uid)
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/general"
+)
+
+// slowStore задерживает GetOrderByUID, пока тест не разрешит ответить
+type slowStore struct {
+	*MemoryStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) GetOrderByUID(uid string, order *general.Order) error {
+	// Ответ «не найден» фиксируется до записи заказа, как у запроса, начатого раньше
+	err := s.MemoryStore.GetOrderByUID(uid, order)
+	close(s.started)
+	<-s.release
+	return err
+}
+
+// Загрузка, начатая до записи заказа из Kafka, не должна запомнить его как отсутствующий
+func TestNegativeCacheIgnoresLoadStartedBeforeWrite(t *testing.T) {
+	store := &slowStore{MemoryStore: NewMemoryStore(), started: make(chan struct{}), release: make(chan struct{})}
+	c := NewCache(10, store, WithNegativeCaching(time.Hour, 100))
+	order := general.Order{OrderUID: "late"}
+
+	loaded := make(chan error)
+	go func() {
+		_, err := c.Get(context.Background(), order.OrderUID)
+		loaded <- err
+	}()
+	<-store.started
+	store.PutOrder(order)
+	c.OrderWritten(order)
+	close(store.release)
+	if err := <-loaded; !errors.Is(err, general.ErrOrderNotFound) {
+		t.Fatalf("первая загрузка: %v", err)
+	}
+
+	if c.negative.contains(order.OrderUID) {
+		t.Fatal("записанный заказ запомнен как отсутствующий")
+	}
+}
+
+func TestNegativeCacheRemembersMissingOrder(t *testing.T) {
+	n := newNegativeCache(time.Hour, 2)
+	n.add("a", n.generation("a"))
+	if !n.contains("a") {
+		t.Fatal("UID не запомнен")
+	}
+	n.forget("a")
+	if n.contains("a") {
+		t.Fatal("UID не забыт после записи")
+	}
+
+	gen := n.generation("b")
+	n.clear()
+	n.add("b", gen)
+	if n.contains("b") {
+		t.Fatal("UID запомнен с поколением до очистки")
+	}
+
+	// Переполнение вытесняет самую старую запись
+	for _, uid := range []string{"c", "d", "e"} {
+		n.add(uid, n.generation(uid))
+	}
+	if n.contains("c") || !n.contains("d") || !n.contains("e") {
+		t.Fatal("при переполнении должна вытесняться самая старая запись")
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+import "fmt"
+
+// Названия поддерживаемых политик вытеснения (значения CACHE_POLICY)
+const (
+	PolicyRandom = "random"
+	PolicyLRU    = "lru"
+	PolicyLFU    = "lfu"
+	PolicyARC    = "arc"
+)
+
+// EvictionPolicy решает, какой заказ вытеснить из кэша при переполнении.
+// Реализации не потокобезопасны: все вызовы выполняются под мьютексом кэша.
+// Все операции выполняются за O(1).
+type EvictionPolicy interface {
+	// Add регистрирует новый ключ
+	Add(uid string)
+	// Touch отмечает обращение к уже существующему ключу
+	Touch(uid string)
+	// Remove забывает ключ, удалённый из кэша не через вытеснение
+	Remove(uid string)
+	// Victim выбирает ключ для вытеснения и перестаёт его отслеживать
+	Victim() (string, bool)
+}
+
+// NewPolicy создаёт политику вытеснения по названию.
+// capacity — максимальное количество записей в кэше (нужно ARC для размера теневых списков)
+func NewPolicy(name string, capacity int) (EvictionPolicy, error) {
+	switch name {
+	case PolicyRandom:
+		return newRandomPolicy(), nil
+	case PolicyLRU:
+		return newLRUPolicy(), nil
+	case PolicyLFU:
+		return newLFUPolicy(), nil
+	case PolicyARC:
+		return newARCPolicy(capacity), nil
+	default:
+		return nil, fmt.Errorf("неизвестная политика вытеснения: %q", name)
+	}
+}
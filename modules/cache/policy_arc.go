@@ -0,0 +1,119 @@
+package cache
+
+import "container/list"
+
+// Списки ARC, в которых может находиться ключ
+const (
+	arcT1 = iota // встречались один раз (недавние)
+	arcT2        // встречались два и более раз (частые)
+	arcB1        // теневой список вытесненных из T1
+	arcB2        // теневой список вытесненных из T2
+)
+
+// arcPolicy — Adaptive Replacement Cache (Megiddo, Modha).
+// Реальные ключи делятся между T1 (недавние) и T2 (частые), а теневые списки
+// B1 и B2 помнят недавно вытесненные ключи. Попадание в теневой список сдвигает
+// целевой размер T1 (p) в сторону того списка, который вытеснил ключ зря.
+type arcPolicy struct {
+	capacity int
+	p        int
+	lists    [4]*list.List
+	items    map[string]*arcEntry
+}
+
+type arcEntry struct {
+	where int
+	el    *list.Element
+}
+
+func newARCPolicy(capacity int) *arcPolicy {
+	if capacity < 1 {
+		capacity = 1
+	}
+	a := &arcPolicy{
+		capacity: capacity,
+		items:    make(map[string]*arcEntry),
+	}
+	for i := range a.lists {
+		a.lists[i] = list.New()
+	}
+	return a
+}
+
+func (a *arcPolicy) Add(uid string) {
+	e, ok := a.items[uid]
+	if !ok {
+		a.items[uid] = &arcEntry{where: arcT1, el: a.lists[arcT1].PushFront(uid)}
+		a.trimGhosts()
+		return
+	}
+	switch e.where {
+	case arcT1, arcT2:
+		a.Touch(uid)
+		return
+	case arcB1:
+		// Ключ вытеснили из T1 слишком рано — увеличиваем долю недавних
+		a.p = min(a.capacity, a.p+max(a.lists[arcB2].Len()/a.lists[arcB1].Len(), 1))
+	case arcB2:
+		// Ключ вытеснили из T2 слишком рано — увеличиваем долю частых
+		a.p = max(0, a.p-max(a.lists[arcB1].Len()/a.lists[arcB2].Len(), 1))
+	}
+	a.move(e, uid, arcT2)
+}
+
+func (a *arcPolicy) Touch(uid string) {
+	e, ok := a.items[uid]
+	if !ok {
+		return
+	}
+	switch e.where {
+	case arcT1:
+		a.move(e, uid, arcT2)
+	case arcT2:
+		a.lists[arcT2].MoveToFront(e.el)
+	}
+}
+
+func (a *arcPolicy) Remove(uid string) {
+	e, ok := a.items[uid]
+	if !ok || (e.where != arcT1 && e.where != arcT2) {
+		return
+	}
+	a.lists[e.where].Remove(e.el)
+	delete(a.items, uid)
+}
+
+func (a *arcPolicy) Victim() (string, bool) {
+	t1, t2 := a.lists[arcT1], a.lists[arcT2]
+	var from, to int
+	switch {
+	case t1.Len() > 0 && (t1.Len() > a.p || t2.Len() == 0):
+		from, to = arcT1, arcB1
+	case t2.Len() > 0:
+		from, to = arcT2, arcB2
+	default:
+		return "", false
+	}
+	uid := a.lists[from].Back().Value.(string)
+	a.move(a.items[uid], uid, to)
+	a.trimGhosts()
+	return uid, true
+}
+
+// move переносит ключ в начало списка to
+func (a *arcPolicy) move(e *arcEntry, uid string, to int) {
+	a.lists[e.where].Remove(e.el)
+	e.where = to
+	e.el = a.lists[to].PushFront(uid)
+}
+
+// trimGhosts ограничивает теневые списки размером кэша
+func (a *arcPolicy) trimGhosts() {
+	for _, ghost := range []int{arcB1, arcB2} {
+		for a.lists[ghost].Len() > a.capacity {
+			el := a.lists[ghost].Back()
+			a.lists[ghost].Remove(el)
+			delete(a.items, el.Value.(string))
+		}
+	}
+}
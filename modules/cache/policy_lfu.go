@@ -0,0 +1,89 @@
+package cache
+
+import "container/list"
+
+// lfuPolicy вытесняет наименее часто используемый ключ,
+// при равной частоте — тот, к которому дольше всего не обращались.
+//
+// Частоты хранятся упорядоченным списком корзин (freqBucket), каждая корзина
+// содержит свой LRU-список ключей. Переход ключа в корзину freq+1 и выбор
+// жертвы из первой корзины выполняются за O(1).
+type lfuPolicy struct {
+	buckets *list.List // *freqBucket по возрастанию частоты
+	items   map[string]*lfuEntry
+}
+
+type freqBucket struct {
+	freq int
+	keys *list.List // ключи с частотой freq, свежие в начале
+}
+
+type lfuEntry struct {
+	bucket *list.Element // элемент p.buckets
+	key    *list.Element // элемент bucket.keys
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{
+		buckets: list.New(),
+		items:   make(map[string]*lfuEntry),
+	}
+}
+
+func (p *lfuPolicy) Add(uid string) {
+	if _, ok := p.items[uid]; ok {
+		p.Touch(uid)
+		return
+	}
+	front := p.buckets.Front()
+	if front == nil || front.Value.(*freqBucket).freq != 1 {
+		front = p.buckets.PushFront(&freqBucket{freq: 1, keys: list.New()})
+	}
+	p.items[uid] = &lfuEntry{
+		bucket: front,
+		key:    front.Value.(*freqBucket).keys.PushFront(uid),
+	}
+}
+
+func (p *lfuPolicy) Touch(uid string) {
+	e, ok := p.items[uid]
+	if !ok {
+		return
+	}
+	cur := e.bucket.Value.(*freqBucket)
+	next := e.bucket.Next()
+	if next == nil || next.Value.(*freqBucket).freq != cur.freq+1 {
+		next = p.buckets.InsertAfter(&freqBucket{freq: cur.freq + 1, keys: list.New()}, e.bucket)
+	}
+	p.unlink(e)
+	e.bucket = next
+	e.key = next.Value.(*freqBucket).keys.PushFront(uid)
+}
+
+func (p *lfuPolicy) Remove(uid string) {
+	e, ok := p.items[uid]
+	if !ok {
+		return
+	}
+	p.unlink(e)
+	delete(p.items, uid)
+}
+
+func (p *lfuPolicy) Victim() (string, bool) {
+	front := p.buckets.Front()
+	if front == nil {
+		return "", false
+	}
+	uid := front.Value.(*freqBucket).keys.Back().Value.(string)
+	p.Remove(uid)
+	return uid, true
+}
+
+// unlink убирает запись из её корзины и удаляет опустевшую корзину
+func (p *lfuPolicy) unlink(e *lfuEntry) {
+	b := e.bucket.Value.(*freqBucket)
+	b.keys.Remove(e.key)
+	if b.keys.Len() == 0 {
+		p.buckets.Remove(e.bucket)
+	}
+}
@@ -0,0 +1,49 @@
+package cache
+
+import "container/list"
+
+// lruPolicy вытесняет ключ, к которому дольше всего не обращались.
+// В начале списка — самые свежие ключи, в конце — кандидаты на вытеснение.
+type lruPolicy struct {
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (p *lruPolicy) Add(uid string) {
+	if el, ok := p.items[uid]; ok {
+		p.order.MoveToFront(el)
+		return
+	}
+	p.items[uid] = p.order.PushFront(uid)
+}
+
+func (p *lruPolicy) Touch(uid string) {
+	if el, ok := p.items[uid]; ok {
+		p.order.MoveToFront(el)
+	}
+}
+
+func (p *lruPolicy) Remove(uid string) {
+	if el, ok := p.items[uid]; ok {
+		p.order.Remove(el)
+		delete(p.items, uid)
+	}
+}
+
+func (p *lruPolicy) Victim() (string, bool) {
+	el := p.order.Back()
+	if el == nil {
+		return "", false
+	}
+	uid := el.Value.(string)
+	p.order.Remove(el)
+	delete(p.items, uid)
+	return uid, true
+}
@@ -0,0 +1,46 @@
+package cache
+
+import "math/rand"
+
+// randomPolicy вытесняет случайный ключ.
+// Ключи хранятся в срезе, а индекс в map позволяет удалять их за O(1)
+// перестановкой с последним элементом.
+type randomPolicy struct {
+	keys  []string
+	index map[string]int
+}
+
+func newRandomPolicy() *randomPolicy {
+	return &randomPolicy{index: make(map[string]int)}
+}
+
+func (p *randomPolicy) Add(uid string) {
+	if _, ok := p.index[uid]; ok {
+		return
+	}
+	p.index[uid] = len(p.keys)
+	p.keys = append(p.keys, uid)
+}
+
+func (p *randomPolicy) Touch(uid string) {}
+
+func (p *randomPolicy) Remove(uid string) {
+	i, ok := p.index[uid]
+	if !ok {
+		return
+	}
+	last := len(p.keys) - 1
+	p.keys[i] = p.keys[last]
+	p.index[p.keys[i]] = i
+	p.keys = p.keys[:last]
+	delete(p.index, uid)
+}
+
+func (p *randomPolicy) Victim() (string, bool) {
+	if len(p.keys) == 0 {
+		return "", false
+	}
+	uid := p.keys[rand.Intn(len(p.keys))]
+	p.Remove(uid)
+	return uid, true
+}
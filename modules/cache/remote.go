@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"project_wb_l0/modules/general"
+	"project_wb_l0/modules/resp"
+	"strconv"
+	"time"
+)
+
+// RemoteTier — второй уровень кэша, общий для всех экземпляров сервиса.
+// Локальная память остаётся первым уровнем (L1), удалённый уровень (L2)
+// опрашивается при промахе L1 до обращения к БД
+type RemoteTier interface {
+	// Get возвращает заказ и признак его наличия
+	Get(ctx context.Context, uid string) (general.Order, bool, error)
+	// Set сохраняет заказ на время ttl (0 — без ограничения)
+	Set(ctx context.Context, uid string, order general.Order, ttl time.Duration) error
+	// Delete удаляет заказ
+	Delete(ctx context.Context, uid string) error
+}
+
+// RedisTier — RemoteTier поверх сервера, говорящего по протоколу Redis.
+// Заказы хранятся в JSON под ключами prefix + uid
+type RedisTier struct {
+	client *resp.Client
+	prefix string
+}
+
+// NewRedisTier создаёт удалённый уровень кэша поверх клиента RESP
+func NewRedisTier(client *resp.Client, prefix string) *RedisTier {
+	return &RedisTier{client: client, prefix: prefix}
+}
+
+func (t *RedisTier) Get(ctx context.Context, uid string) (general.Order, bool, error) {
+	v, err := t.client.Do(ctx, "GET", t.prefix+uid)
+	if err != nil {
+		return general.Order{}, false, fmt.Errorf("ошибка чтения из Redis: %w", err)
+	}
+	if v.Null {
+		return general.Order{}, false, nil
+	}
+	var order general.Order
+	if err := json.Unmarshal([]byte(v.Str), &order); err != nil {
+		return general.Order{}, false, fmt.Errorf("повреждённый заказ %s в Redis: %w", uid, err)
+	}
+	return order, true, nil
+}
+
+func (t *RedisTier) Set(ctx context.Context, uid string, order general.Order, ttl time.Duration) error {
+	body, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", t.prefix + uid, string(body)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(max(1, ttl.Milliseconds()), 10))
+	}
+	if _, err := t.client.Do(ctx, args...); err != nil {
+		return fmt.Errorf("ошибка записи в Redis: %w", err)
+	}
+	return nil
+}
+
+func (t *RedisTier) Delete(ctx context.Context, uid string) error {
+	if _, err := t.client.Do(ctx, "DEL", t.prefix+uid); err != nil {
+		return fmt.Errorf("ошибка удаления из Redis: %w", err)
+	}
+	return nil
+}
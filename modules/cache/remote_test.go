@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/general"
+	"project_wb_l0/modules/resp"
+)
+
+// startRedis запускает RESP-сервер в памяти и RedisTier поверх него
+func startRedis(t *testing.T) (*resp.Server, *RedisTier) {
+	t.Helper()
+	srv, err := resp.NewServer("127.0.0.1:0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := resp.NewClient(srv.Addr(), "", 2, time.Second)
+	t.Cleanup(func() {
+		client.Close()
+		srv.Close()
+	})
+	return srv, NewRedisTier(client, "order:")
+}
+
+func TestRedisTier(t *testing.T) {
+	_, tier := startRedis(t)
+	ctx := context.Background()
+
+	if _, ok, err := tier.Get(ctx, "a"); err != nil || ok {
+		t.Fatalf("Get отсутствующего: ok=%v, %v", ok, err)
+	}
+	want := general.Order{OrderUID: "a", TrackNumber: "track-a", Items: []general.Item{{Name: "item"}}}
+	if err := tier.Set(ctx, "a", want, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := tier.Get(ctx, "a")
+	if err != nil || !ok || got.TrackNumber != want.TrackNumber || len(got.Items) != 1 {
+		t.Fatalf("Get = %+v, ok=%v, %v", got, ok, err)
+	}
+	if err := tier.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := tier.Get(ctx, "a"); ok {
+		t.Fatal("заказ остался после Delete")
+	}
+
+	if err := tier.Set(ctx, "b", want, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok, _ := tier.Get(ctx, "b"); ok {
+		t.Fatal("заказ пережил ttl")
+	}
+}
+
+func TestRemoteTierSharedBetweenInstances(t *testing.T) {
+	_, tier := startRedis(t)
+	ctx := context.Background()
+	store := NewMemoryStore()
+	store.PutOrder(general.Order{OrderUID: "a", TrackNumber: "track-a"})
+
+	// Первый экземпляр загружает заказ из БД и кладёт его во второй уровень
+	first := NewCache(10, store, WithRemoteTier(tier, time.Minute))
+	if _, err := first.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if s := first.Stats(); s.Loads != 1 || s.RemoteMisses != 1 {
+		t.Fatalf("первый экземпляр: %+v", s)
+	}
+
+	// Второй находит его во втором уровне, не обращаясь к БД
+	second := NewCache(10, store, WithRemoteTier(tier, time.Minute))
+	order, err := second.Get(ctx, "a")
+	if err != nil || order.TrackNumber != "track-a" {
+		t.Fatalf("второй экземпляр: %+v, %v", order, err)
+	}
+	if s := second.Stats(); s.Loads != 0 || s.RemoteHits != 1 {
+		t.Fatalf("второй экземпляр: %+v", s)
+	}
+
+	// Удаление на одном экземпляре убирает заказ и из второго уровня
+	first.Delete("a")
+	if _, ok, _ := tier.Get(ctx, "a"); ok {
+		t.Fatal("заказ остался во втором уровне после Delete")
+	}
+	third := NewCache(10, store, WithRemoteTier(tier, time.Minute))
+	if _, err := third.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if s := third.Stats(); s.Loads != 1 || s.RemoteMisses != 1 {
+		t.Fatalf("после удаления заказ должен читаться из БД: %+v", s)
+	}
+}
+
+func TestRemoteTierUnavailable(t *testing.T) {
+	srv, tier := startRedis(t)
+	srv.Close()
+	store := NewMemoryStore()
+	store.PutOrder(general.Order{OrderUID: "a", TrackNumber: "track-a"})
+
+	// Ошибки второго уровня не мешают читать из БД
+	c := NewCache(10, store, WithRemoteTier(tier, time.Minute))
+	order, err := c.Get(context.Background(), "a")
+	if err != nil || order.TrackNumber != "track-a" {
+		t.Fatalf("Get = %+v, %v", order, err)
+	}
+	if s := c.Stats(); s.RemoteErrors != 2 || s.Loads != 1 {
+		t.Fatalf("ожидались две ошибки второго уровня (чтение и запись): %+v", s)
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// RestoreSummary — итог восстановления кэша из БД
+type RestoreSummary struct {
+	Total    int           // UID'ов в таблице Hash
+	Loaded   int           // заказов восстановлено в кэш
+	Missing  int           // UID'ов, для которых заказа уже нет в БД
+	Failed   int           // UID'ов, не загруженных из-за ошибок БД
+	Errors   []error       // ошибки загрузки пачек
+	Duration time.Duration // сколько длилось восстановление
+}
+
+func (s RestoreSummary) String() string {
+	return fmt.Sprintf("всего %d, восстановлено %d, отсутствует в БД %d, ошибок %d, за %v",
+		s.Total, s.Loaded, s.Missing, s.Failed, s.Duration.Round(time.Millisecond))
+}
+
+// RestoreFromDB восстанавливает кэш из БД:
+// 1. Забирает все order_id из таблицы Hash
+// 2. Делит их на пачки и загружает каждую пачку одним запросом GetOrdersByUIDs
+// 3. Сохраняет заказы в локальный кэш, а UID'ы заказов, которых в БД уже нет, удаляет из Hash
+//
+// Пачки загружаются параллельно несколькими горутинами. Глобальной блокировки нет:
+// кэш продолжает обслуживать чтения, пока идёт восстановление.
+// Ошибки отдельных пачек не прерывают восстановление и попадают в итог
+func (c *Cache) RestoreFromDB(ctx context.Context) (RestoreSummary, error) {
+	start := time.Now()
+	var summary RestoreSummary
+
+	// Шаг 1: Получаем список UID'ов из Hash
+	uids, err := c.orders.GetAllHashUIDs()
+	if err != nil {
+		return summary, fmt.Errorf("ошибка получения UID'ов из Hash: %w", err)
+	}
+	summary.Total = len(uids)
+
+	log.Printf("Начинаем восстановление кэша из БД. Найдено записей: %d\n", len(uids))
+
+	batches := make(chan []string)
+	go func() {
+		defer close(batches)
+		for i := 0; i < len(uids); i += c.restoreBatch {
+			select {
+			case batches <- uids[i:min(i+c.restoreBatch, len(uids))]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Шаг 2: Загружаем пачки параллельно и кладём заказы в кэш
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for range c.restoreWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				orders, err := c.orders.GetOrdersByUIDs(batch)
+
+				loaded := 0
+				if err == nil {
+					for uid, order := range orders {
+						// UID уже есть в Hash, поэтому сохраняем только в память
+						_, evicted := c.shardFor(uid).store(uid, order, c.ttl, true)
+						c.dropEvicted(evicted)
+					}
+					loaded = len(orders)
+
+					// Заказов, которых уже нет в БД, не будет и в кэше — убираем их из Hash
+					var missing []string
+					for _, uid := range batch {
+						if _, ok := orders[uid]; !ok {
+							missing = append(missing, uid)
+						}
+					}
+					c.syncHash(missing...)
+				}
+
+				mu.Lock()
+				if err != nil {
+					summary.Failed += len(batch)
+					summary.Errors = append(summary.Errors, err)
+					log.Printf("Не удалось восстановить пачку из %d заказов: %v", len(batch), err)
+				} else {
+					summary.Loaded += loaded
+					summary.Missing += len(batch) - loaded
+				}
+				log.Printf("Восстановление кэша: обработано %d из %d",
+					summary.Loaded+summary.Missing+summary.Failed, summary.Total)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	summary.Duration = time.Since(start)
+	if err := ctx.Err(); err != nil {
+		return summary, fmt.Errorf("восстановление кэша прервано: %w", err)
+	}
+	return summary, nil
+}
@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"project_wb_l0/modules/general"
+	"sync"
+	"time"
+)
+
+// shard — независимый сегмент кэша со своей блокировкой и политикой вытеснения.
+// Ёмкость ограничивается либо количеством записей (maxItems), либо, если задан
+// maxBytes, суммарным оценочным размером заказов в байтах.
+// Методы shard не обращаются к БД: UID'ы, которые нужно добавить в таблицу Hash
+// или удалить из неё, возвращаются вызывающему и обрабатываются после снятия блокировки
+type shard struct {
+	mu       sync.Mutex
+	maxItems int
+	maxBytes int64
+	bytes    int64
+	data     map[string]*entry
+	policy   EvictionPolicy
+}
+
+// entry — запись кэша вместе со сроком её жизни
+type entry struct {
+	order     general.Order
+	size      int64 // оценка занимаемой памяти, считается только при maxBytes > 0
+	ttl       time.Duration
+	expiresAt time.Time // нулевое значение — запись не истекает
+}
+
+func newEntry(order general.Order, size int64, ttl time.Duration, now time.Time) *entry {
+	e := &entry{order: order, size: size}
+	e.refresh(ttl, now)
+	return e
+}
+
+// refresh продлевает жизнь записи на ttl начиная с now
+func (e *entry) refresh(ttl time.Duration, now time.Time) {
+	e.ttl = ttl
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	} else {
+		e.expiresAt = time.Time{}
+	}
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+func newShard(maxItems int, maxBytes int64, policy EvictionPolicy) *shard {
+	return &shard{
+		maxItems: maxItems,
+		maxBytes: maxBytes,
+		data:     make(map[string]*entry),
+		policy:   policy,
+	}
+}
+
+// full сообщает, нужно ли освободить место перед добавлением записи размера size
+func (s *shard) full(size int64) bool {
+	if s.maxBytes > 0 {
+		return s.bytes+size > s.maxBytes
+	}
+	return len(s.data) >= s.maxItems
+}
+
+// lookup ищет живую запись. Истёкшая запись удаляется (expired = true),
+// а при sliding срок жизни найденной записи продлевается
+func (s *shard) lookup(uid string, sliding bool) (order general.Order, ok, expired bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.data[uid]
+	if !ok {
+		return general.Order{}, false, false
+	}
+	now := time.Now()
+	if e.expired(now) {
+		s.remove(uid)
+		return general.Order{}, false, true
+	}
+	s.policy.Touch(uid)
+	if sliding {
+		e.refresh(e.ttl, now)
+	}
+	return e.order, true, false
+}
+
+// store кладёт заказ в сегмент, при необходимости вытесняя записи по политике.
+// При overwrite = false живая запись с тем же UID остаётся нетронутой.
+// Возвращает, добавлен ли новый ключ, и список вытесненных UID'ов
+func (s *shard) store(uid string, order general.Order, ttl time.Duration, overwrite bool) (added bool, evicted []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e, exists := s.data[uid]
+	if exists && !overwrite && !e.expired(now) {
+		return false, nil
+	}
+
+	var size int64
+	if s.maxBytes > 0 {
+		size = orderSize(uid, order)
+		if size > s.maxBytes {
+			// Заказ больше всего бюджета сегмента — не кэшируем,
+			// а старую версию выбрасываем, чтобы не отдавать устаревшие данные
+			if exists {
+				s.remove(uid)
+				evicted = append(evicted, uid)
+			}
+			return false, evicted
+		}
+	}
+
+	if exists {
+		s.bytes += size - e.size
+		e.order = order
+		e.size = size
+		e.refresh(ttl, now)
+		s.policy.Touch(uid)
+		// Обновлённый заказ мог стать больше — освобождаем место (возможно, и от него самого)
+		for s.maxBytes > 0 && s.bytes > s.maxBytes {
+			victim, ok := s.evict()
+			if !ok {
+				break
+			}
+			evicted = append(evicted, victim)
+		}
+		return false, evicted
+	}
+
+	for s.full(size) {
+		victim, ok := s.evict()
+		if !ok {
+			break
+		}
+		evicted = append(evicted, victim)
+	}
+	s.data[uid] = newEntry(order, size, ttl, now)
+	s.bytes += size
+	s.policy.Add(uid)
+	return true, evicted
+}
+
+// evict удаляет выбранную политикой запись. Вызывается под s.mu
+func (s *shard) evict() (string, bool) {
+	victim, ok := s.policy.Victim()
+	if !ok {
+		return "", false
+	}
+	s.bytes -= s.data[victim].size
+	delete(s.data, victim)
+	return victim, true
+}
+
+// remove удаляет запись из сегмента и политики. Вызывается под s.mu
+func (s *shard) remove(uid string) bool {
+	e, ok := s.data[uid]
+	if !ok {
+		return false
+	}
+	s.bytes -= e.size
+	delete(s.data, uid)
+	s.policy.Remove(uid)
+	return true
+}
+
+// setTTL меняет время жизни, на которое продлевается запись, не трогая текущий срок
+func (s *shard) setTTL(uid string, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.data[uid]; ok {
+		e.ttl = ttl
+	}
+}
+
+// deleteExpired удаляет все истёкшие записи и возвращает их UID'ы
+func (s *shard) deleteExpired() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var removed []string
+	for uid, e := range s.data {
+		if e.expired(now) {
+			s.remove(uid)
+			removed = append(removed, uid)
+		}
+	}
+	return removed
+}
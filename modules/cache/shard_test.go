@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"project_wb_l0/modules/general"
+)
+
+const benchOrders = 10000
+
+// benchCache создаёт кэш из shards сегментов с benchOrders заказами в хранилище
+func benchCache(b *testing.B, shards int) (*Cache, []string) {
+	b.Helper()
+	// Кэш пишет в лог на каждое обращение — в бенчмарке это только шум
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	store := NewMemoryStore()
+	uids := make([]string, benchOrders)
+	for i := range uids {
+		uids[i] = "order" + strconv.Itoa(i)
+		store.PutOrder(general.Order{OrderUID: uids[i]})
+	}
+	// С запасом по ёмкости: хэш делит заказы между сегментами неровно, а вытеснения
+	// превратили бы бенчмарк чтения в бенчмарк загрузки из хранилища
+	return NewCache(4*benchOrders, store, WithShards(shards)), uids
+}
+
+// benchShardCounts — один сегмент против нескольких. Запускать стоит с -cpu 1,4,8:
+// выигрыш от сегментов виден только при параллельных обращениях
+var benchShardCounts = []int{1, 4, 16, 64}
+
+func BenchmarkCacheParallelGet(b *testing.B) {
+	for _, shards := range benchShardCounts {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c, uids := benchCache(b, shards)
+			for _, uid := range uids {
+				c.Set(uid, general.Order{OrderUID: uid})
+			}
+			ctx := context.Background()
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(next.Add(1)) * 7919
+				for pb.Next() {
+					if _, err := c.Get(ctx, uids[i%len(uids)]); err != nil {
+						b.Error(err)
+						return
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkCacheParallelSet(b *testing.B) {
+	for _, shards := range benchShardCounts {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c, uids := benchCache(b, shards)
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(next.Add(1)) * 7919
+				for pb.Next() {
+					uid := uids[i%len(uids)]
+					c.Set(uid, general.Order{OrderUID: uid})
+					i++
+				}
+			})
+		})
+	}
+}
+
+func TestHashUIDIsFNV1a(t *testing.T) {
+	for _, uid := range []string{"", "a", "b563feb7b2b84b6test"} {
+		h := fnv.New32a()
+		h.Write([]byte(uid))
+		if got, want := hashUID(uid), h.Sum32(); got != want {
+			t.Fatalf("hashUID(%q) = %d, ожидалось %d", uid, got, want)
+		}
+	}
+}
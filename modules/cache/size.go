@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"project_wb_l0/modules/general"
+	"unsafe"
+)
+
+// Фиксированные накладные расходы на хранение одной записи
+var (
+	orderOverhead = int64(unsafe.Sizeof(general.Order{}))
+	itemOverhead  = int64(unsafe.Sizeof(general.Item{}))
+	// сама запись, ключ в map и служебные структуры политики вытеснения (оценка)
+	entryOverhead = int64(unsafe.Sizeof(entry{})) + 64
+)
+
+// orderSize оценивает, сколько байт памяти занимает заказ в кэше:
+// размеры структур плюс содержимое всех строк и элементов Items
+func orderSize(uid string, order general.Order) int64 {
+	size := entryOverhead + orderOverhead + int64(len(uid))
+	size += strLen(
+		order.OrderUID, order.TrackNumber, order.Entry, order.Locale,
+		order.InternalSignature, order.CustomerID, order.DeliveryService,
+		order.Shardkey, order.SmID, order.OofShard,
+	)
+
+	d := order.Delivery
+	size += strLen(d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email)
+
+	p := order.Payment
+	size += strLen(p.Transaction, p.RequestID, p.Currency, p.Provider, p.Bank)
+
+	size += int64(cap(order.Items)) * itemOverhead
+	for _, it := range order.Items {
+		size += strLen(it.ChrtID, it.TrackNumber, it.Rid, it.Name, it.Size, it.NmID, it.Brand)
+	}
+	return size
+}
+
+func strLen(values ...string) int64 {
+	var n int64
+	for _, v := range values {
+		n += int64(len(v))
+	}
+	return n
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"project_wb_l0/modules/general"
+	"time"
+)
+
+// Формат файла снимка:
+//
+//	snapshotMagic | sha256(payload) | payload
+//
+// где payload — gzip-сжатый gob снимка snapshot
+var snapshotMagic = []byte("WBCACHE1")
+
+// ErrSnapshotCorrupt — файл снимка повреждён или имеет чужой формат
+var ErrSnapshotCorrupt = errors.New("снимок кэша повреждён")
+
+type snapshot struct {
+	SavedAt time.Time
+	Entries []snapshotEntry
+}
+
+type snapshotEntry struct {
+	UID       string
+	Order     general.Order
+	TTL       time.Duration
+	ExpiresAt time.Time
+}
+
+// SaveSnapshot сохраняет всё содержимое кэша в файл path.
+// Файл сначала пишется во временный и затем атомарно переименовывается
+func (c *Cache) SaveSnapshot(path string) error {
+	snap := snapshot{SavedAt: time.Now()}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		for uid, e := range sh.data {
+			snap.Entries = append(snap.Entries, snapshotEntry{
+				UID:       uid,
+				Order:     e.order,
+				TTL:       e.ttl,
+				ExpiresAt: e.expiresAt,
+			})
+		}
+		sh.mu.Unlock()
+	}
+
+	var payload bytes.Buffer
+	zw := gzip.NewWriter(&payload)
+	if err := gob.NewEncoder(zw).Encode(snap); err != nil {
+		return fmt.Errorf("ошибка кодирования снимка кэша: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("ошибка сжатия снимка кэша: %w", err)
+	}
+	sum := sha256.Sum256(payload.Bytes())
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла снимка: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	for _, chunk := range [][]byte{snapshotMagic, sum[:], payload.Bytes()} {
+		if _, err := tmp.Write(chunk); err != nil {
+			tmp.Close()
+			return fmt.Errorf("ошибка записи снимка кэша: %w", err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ошибка записи снимка кэша: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("ошибка сохранения снимка кэша: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot загружает кэш из файла path, проверив контрольную сумму.
+// Истёкшие записи пропускаются. Пока экземпляр был остановлен, другие могли
+// изменить заказы, поэтому загруженные записи сверяются с БД: изменённые
+// обновляются, удалённые выбрасываются. После успешной загрузки файл удаляется,
+// чтобы после аварийного завершения не поднять устаревший снимок.
+// Возвращает количество загруженных записей
+func (c *Cache) LoadSnapshot(path string) (int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения снимка кэша: %w", err)
+	}
+
+	header := len(snapshotMagic) + sha256.Size
+	if len(raw) < header || !bytes.Equal(raw[:len(snapshotMagic)], snapshotMagic) {
+		return 0, fmt.Errorf("%w: неизвестный формат", ErrSnapshotCorrupt)
+	}
+	payload := raw[header:]
+	sum := sha256.Sum256(payload)
+	if !bytes.Equal(sum[:], raw[len(snapshotMagic):header]) {
+		return 0, fmt.Errorf("%w: контрольная сумма не совпадает", ErrSnapshotCorrupt)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	var snap snapshot
+	if err := gob.NewDecoder(zr).Decode(&snap); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	now := time.Now()
+	for _, se := range snap.Entries {
+		if !se.ExpiresAt.IsZero() && now.After(se.ExpiresAt) {
+			continue
+		}
+		ttl := time.Duration(0)
+		if !se.ExpiresAt.IsZero() {
+			ttl = se.ExpiresAt.Sub(now)
+		}
+		// UID уже есть в Hash, поэтому сохраняем только в память
+		_, evicted := c.shardFor(se.UID).store(se.UID, se.Order, ttl, true)
+		c.dropEvicted(evicted)
+		// Дальше запись живёт по своему исходному TTL (важно для скользящего продления)
+		c.shardFor(se.UID).setTTL(se.UID, se.TTL)
+	}
+
+	if err := os.Remove(path); err != nil {
+		log.Printf("Не удалось удалить загруженный снимок кэша: %v", err)
+	}
+
+	refreshed, total := c.refreshAll()
+	log.Printf("Снимок кэша сверен с БД: актуальны %d из %d", refreshed, total)
+	return refreshed, nil
+}
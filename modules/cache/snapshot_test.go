@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/general"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	src, store := newTestCache(10, []string{"a", "b", "c"})
+	ctx := context.Background()
+	for _, uid := range []string{"a", "b", "c"} {
+		if _, err := src.Get(ctx, uid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+
+	dst := NewCache(10, store)
+	n, err := dst.LoadSnapshot(path)
+	if err != nil || n != 3 {
+		t.Fatalf("LoadSnapshot = %d, %v; ожидалось 3 записи", n, err)
+	}
+	for _, uid := range []string{"a", "b", "c"} {
+		order, hit, err := dst.Fetch(ctx, uid)
+		if err != nil || !hit || order.TrackNumber != "track-"+uid {
+			t.Fatalf("%s после загрузки: %+v, hit=%v, %v", uid, order, hit, err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("загруженный снимок не удалён: %v", err)
+	}
+}
+
+func TestSnapshotReconciledWithDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	src, store := newTestCache(10, []string{"a", "b"})
+	src.Set("a", general.Order{OrderUID: "a", TrackNumber: "old"})
+	src.Set("b", general.Order{OrderUID: "b", TrackNumber: "old"})
+	if err := src.SaveSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Пока экземпляр был остановлен, другой изменил заказ a, а заказ b удалили
+	store.PutOrder(general.Order{OrderUID: "a", TrackNumber: "new"})
+	delete(store.orders, "b")
+
+	dst := NewCache(10, store)
+	n, err := dst.LoadSnapshot(path)
+	if err != nil || n != 1 {
+		t.Fatalf("LoadSnapshot = %d, %v; ожидалась 1 актуальная запись", n, err)
+	}
+	order, hit, _ := dst.Fetch(context.Background(), "a")
+	if !hit || order.TrackNumber != "new" {
+		t.Fatalf("a после загрузки: %+v, hit=%v; ожидалась версия из БД", order, hit)
+	}
+	if contains(dst.Keys(), "b") {
+		t.Fatal("удалённый из БД заказ b загружен из снимка")
+	}
+}
+
+func TestSnapshotSkipsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	src, store := newTestCache(10, []string{"a", "b"})
+	src.SetWithTTL("a", general.Order{OrderUID: "a"}, 20*time.Millisecond)
+	src.SetWithTTL("b", general.Order{OrderUID: "b"}, time.Hour)
+	if err := src.SaveSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+
+	dst := NewCache(10, store)
+	n, err := dst.LoadSnapshot(path)
+	if err != nil || n != 1 {
+		t.Fatalf("LoadSnapshot = %d, %v; ожидалась 1 запись", n, err)
+	}
+	if keys := dst.Keys(); len(keys) != 1 || keys[0] != "b" {
+		t.Fatalf("в кэше %v, ожидался только b", keys)
+	}
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	src, store := newTestCache(10, []string{"a"})
+	src.Set("a", general.Order{OrderUID: "a"})
+	good := filepath.Join(dir, "good.snapshot")
+	if err := src.SaveSnapshot(good); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := append([]byte(nil), raw...)
+	flipped[len(flipped)-1] ^= 0xff
+	cases := map[string][]byte{
+		"контрольная сумма": flipped,
+		"чужой формат":      append([]byte("NOTCACHE"), raw[8:]...),
+		"обрезанный файл":   raw[:10],
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "bad.snapshot")
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			c := NewCache(10, store)
+			if _, err := c.LoadSnapshot(path); !errors.Is(err, ErrSnapshotCorrupt) {
+				t.Fatalf("ожидалась ErrSnapshotCorrupt, получено %v", err)
+			}
+			if n := len(c.Keys()); n != 0 {
+				t.Fatalf("из повреждённого снимка загружено %d записей", n)
+			}
+		})
+	}
+}
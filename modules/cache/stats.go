@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// counters — счётчики работы кэша, обновляются без блокировок
+type counters struct {
+	hits         atomic.Int64
+	misses       atomic.Int64
+	negativeHits atomic.Int64
+	evictions    atomic.Int64
+	expirations  atomic.Int64
+	loads        atomic.Int64
+	loadErrors   atomic.Int64
+	loadNanos    atomic.Int64
+	remoteHits   atomic.Int64
+	remoteMisses atomic.Int64
+	remoteErrors atomic.Int64
+}
+
+// Stats — снимок статистики кэша
+type Stats struct {
+	Items            int     `json:"items"`
+	Bytes            int64   `json:"bytes"`
+	Shards           int     `json:"shards"`
+	Hits             int64   `json:"hits"`
+	Misses           int64   `json:"misses"`
+	NegativeHits     int64   `json:"negative_hits"`
+	HitRatio         float64 `json:"hit_ratio"`
+	Evictions        int64   `json:"evictions"`
+	Expirations      int64   `json:"expirations"`
+	Loads            int64   `json:"loads"`
+	LoadErrors       int64   `json:"load_errors"`
+	AvgLoadLatencyMs float64 `json:"avg_load_latency_ms"`
+	RemoteHits       int64   `json:"remote_hits"`
+	RemoteMisses     int64   `json:"remote_misses"`
+	RemoteErrors     int64   `json:"remote_errors"`
+}
+
+// Stats возвращает текущую статистику кэша
+func (c *Cache) Stats() Stats {
+	s := Stats{
+		Shards:       len(c.shards),
+		Hits:         c.stats.hits.Load(),
+		Misses:       c.stats.misses.Load(),
+		NegativeHits: c.stats.negativeHits.Load(),
+		Evictions:    c.stats.evictions.Load(),
+		Expirations:  c.stats.expirations.Load(),
+		Loads:        c.stats.loads.Load(),
+		LoadErrors:   c.stats.loadErrors.Load(),
+		RemoteHits:   c.stats.remoteHits.Load(),
+		RemoteMisses: c.stats.remoteMisses.Load(),
+		RemoteErrors: c.stats.remoteErrors.Load(),
+	}
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		s.Items += len(sh.data)
+		s.Bytes += sh.bytes
+		sh.mu.Unlock()
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRatio = float64(s.Hits) / float64(total)
+	}
+	if s.Loads > 0 {
+		avg := time.Duration(c.stats.loadNanos.Load() / s.Loads)
+		s.AvgLoadLatencyMs = float64(avg) / float64(time.Millisecond)
+	}
+	return s
+}
+
+// Keys возвращает отсортированный список UID'ов, находящихся в кэше
+func (c *Cache) Keys() []string {
+	var keys []string
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		for uid := range sh.data {
+			keys = append(keys, uid)
+		}
+		sh.mu.Unlock()
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Delete удаляет заказ из кэша, из таблицы Hash и из второго уровня кэша.
+// Возвращает false, если заказа в локальном кэше не было
+func (c *Cache) Delete(uid string) bool {
+	sh := c.shardFor(uid)
+	sh.mu.Lock()
+	removed := sh.remove(uid)
+	sh.mu.Unlock()
+	if removed {
+		c.syncHash(uid)
+	}
+	c.remoteDelete(uid)
+	return removed
+}
+
+// Flush очищает весь локальный кэш вместе с таблицей Hash и негативным кэшем.
+// Второй уровень общий для всех экземпляров и не очищается.
+// Возвращает количество удалённых записей
+func (c *Cache) Flush() int {
+	var removed []string
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		for uid := range sh.data {
+			sh.remove(uid)
+			removed = append(removed, uid)
+		}
+		sh.mu.Unlock()
+	}
+	c.syncHash(removed...)
+	c.negative.clear()
+	return len(removed)
+}
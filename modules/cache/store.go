@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"fmt"
+	"project_wb_l0/modules/general"
+	"sort"
+	"sync"
+)
+
+// OrderStore — постоянное хранилище, на которое опирается кэш: из него загружаются
+// заказы при промахе, и в нём хранится список закэшированных UID'ов (таблица Hash).
+// *database.Db реализует этот интерфейс
+type OrderStore interface {
+	// GetOrderByUID загружает заказ; если его нет, возвращает ошибку,
+	// оборачивающую general.ErrOrderNotFound
+	GetOrderByUID(uid string, order *general.Order) error
+	// GetOrdersByUIDs загружает несколько заказов одним запросом;
+	// отсутствующих заказов в результате нет
+	GetOrdersByUIDs(uids []string) (map[string]general.Order, error)
+	// SaveOrderToCacheBd запоминает, что uid находится в кэше
+	SaveOrderToCacheBd(uid string) error
+	// RemoveFromHash забывает, что uid находится в кэше
+	RemoveFromHash(uid string) error
+	// GetAllHashUIDs возвращает все UID'ы, которые были в кэше
+	GetAllHashUIDs() ([]string, error)
+}
+
+// MemoryStore — реализация OrderStore в памяти, не требующая Postgres.
+// Подходит для тестов и локального запуска кэша
+type MemoryStore struct {
+	mu     sync.RWMutex
+	orders map[string]general.Order
+	hash   map[string]struct{}
+}
+
+// NewMemoryStore создаёт пустое хранилище
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		orders: make(map[string]general.Order),
+		hash:   make(map[string]struct{}),
+	}
+}
+
+// PutOrder добавляет или заменяет заказ в хранилище
+func (s *MemoryStore) PutOrder(order general.Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[order.OrderUID] = order
+}
+
+func (s *MemoryStore) GetOrderByUID(uid string, order *general.Order) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o, ok := s.orders[uid]
+	if !ok {
+		return fmt.Errorf("не найдено в Orders: %w", general.ErrOrderNotFound)
+	}
+	*order = o
+	return nil
+}
+
+func (s *MemoryStore) GetOrdersByUIDs(uids []string) (map[string]general.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	orders := make(map[string]general.Order, len(uids))
+	for _, uid := range uids {
+		if o, ok := s.orders[uid]; ok {
+			orders[uid] = o
+		}
+	}
+	return orders, nil
+}
+
+func (s *MemoryStore) SaveOrderToCacheBd(uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.orders[uid]; !ok {
+		// В Postgres Hash ссылается на Orders внешним ключом
+		return fmt.Errorf("ошибка сохранения Hash: заказа %s нет в Orders", uid)
+	}
+	s.hash[uid] = struct{}{}
+	return nil
+}
+
+func (s *MemoryStore) RemoveFromHash(uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.hash[uid]; !ok {
+		return fmt.Errorf("запись с UID %s не найдена в Hash", uid)
+	}
+	delete(s.hash, uid)
+	return nil
+}
+
+// GetAllHashUIDs возвращает UID'ы из Hash в отсортированном порядке
+func (s *MemoryStore) GetAllHashUIDs() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	uids := make([]string, 0, len(s.hash))
+	for uid := range s.hash {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids, nil
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,24 +15,99 @@ var (
 	DBName     = getEnv("DB_NAME", "order_db")
 	DBHost     = getEnv("DB_HOST", "localhost")
 	DBPort     = getEnv("DB_PORT", "5432")
+	// Повтор записи из Kafka при временных ошибках БД: число попыток и границы задержки
+	DBRetryAttempts  = getEnvAsInt("DB_RETRY_ATTEMPTS", 5)
+	DBRetryBaseDelay = time.Millisecond * time.Duration(getEnvAsInt("DB_RETRY_BASE_MS", 100))
+	DBRetryMaxDelay  = time.Millisecond * time.Duration(getEnvAsInt("DB_RETRY_MAX_MS", 5000))
+	// Что делать, когда попытки кончились: "deadletter" — отправить в dead-letter топик, "halt" — остановить чтение.
+	// Без KAFKA_DLQ_TOPIC всегда "halt"
+	DBRetryExhausted = getEnv("DB_RETRY_EXHAUSTED", "deadletter")
 )
 
 // Конфигурация Kafka
 var (
-	KafkaBroker    = getEnv("KAFKA_BROKER", "localhost:9092")
-	KafkaTopic     = getEnv("KAFKA_TOPIC", "order-info")
-	KafkaGroupID   = getEnv("KAFKA_GROUP_ID", "OrderToBd")
-	KafkaFetchWait = time.Second * time.Duration(getEnvAsInt("KAFKA_FETCH_WAIT", 5))
+	KafkaBroker  = getEnv("KAFKA_BROKER", "localhost:9092")
+	KafkaTopic   = getEnv("KAFKA_TOPIC", "order-info")
+	KafkaGroupID = getEnv("KAFKA_GROUP_ID", "OrderToBd")
+	// Топики смен статусов и отмен заказов (например, order-status и order-cancel), читаются в той же группе. Пусто — не читать
+	KafkaStatusTopic = getEnv("KAFKA_STATUS_TOPIC", "")
+	KafkaCancelTopic = getEnv("KAFKA_CANCEL_TOPIC", "")
+	// Минимальный интервал между пачками в секундах, общий для всех обработчиков
+	// (0 — читать без ограничений). За один интервал проходит до KAFKA_BATCH_SIZE сообщений
+	KafkaFetchWait = time.Second * time.Duration(getEnvAsInt("KAFKA_FETCH_WAIT", 0))
+	// Максимальный размер пачки сообщений, записываемой в БД одной транзакцией
+	KafkaBatchSize = getEnvAsInt("KAFKA_BATCH_SIZE", 100)
+	// Сколько ждать добора пачки после первого сообщения, в миллисекундах
+	KafkaBatchTimeout = time.Millisecond * time.Duration(getEnvAsInt("KAFKA_BATCH_TIMEOUT_MS", 500))
+	// Число параллельных обработчиков; партиция всегда обрабатывается одним из них
+	KafkaWorkers = getEnvAsInt("KAFKA_WORKERS", 4)
+	// Проверять сообщения по JSON Schema заказа; в строгом режиме (по умолчанию) отклонять неизвестные поля
+	SchemaValidation = getEnvAsBool("SCHEMA_VALIDATION", true)
+	SchemaStrict     = getEnvAsBool("SCHEMA_STRICT", true)
+	// Реестр схем для сообщений в Avro и Protobuf (пусто — без реестра)
+	SchemaRegistryURL     = getEnv("SCHEMA_REGISTRY_URL", "")
+	SchemaRegistryTimeout = time.Millisecond * time.Duration(getEnvAsInt("SCHEMA_REGISTRY_TIMEOUT_MS", 2000))
+	// Адрес встроенного реестра схем в памяти (пусто — не запускать); если SCHEMA_REGISTRY_URL
+	// не задан, консюмер использует его, а схемы заказа регистрируются в нём при старте
+	SchemaRegistryEmbeddedAddr = getEnv("SCHEMA_REGISTRY_EMBEDDED_ADDR", "")
+	// Файл с правилами валидации заказов (YAML или JSON; пусто — встроенные правила)
+	ValidationRulesPath = getEnv("VALIDATION_RULES", "")
+	// Топик для сообщений, которые не удалось обработать (пусто — только писать в лог)
+	KafkaDLQTopic = getEnv("KAFKA_DLQ_TOPIC", "")
+)
+
+// Конфигурация проверки сумм заказов
+var (
+	FinanceCheck = getEnvAsBool("FINANCE_CHECK", true)
+	// Допустимая погрешность округления для валют, не указанных в FINANCE_TOLERANCES
+	FinanceDefaultTolerance = getEnvAsFloat("FINANCE_DEFAULT_TOLERANCE", 0.01)
+	// Погрешность по валютам, например "USD=0.01,RUB=1,JPY=1"
+	FinanceTolerances = getEnvAsFloatMap("FINANCE_TOLERANCES")
 )
 
 // Конфигурация HTTP-сервера
 var (
 	ServerAddr = getEnv("SERVER_ADDR", ":5000")
+	// Токен для /admin/* (пусто — маршруты /admin/* отключены)
+	AdminToken = getEnv("ADMIN_TOKEN", "")
 )
 
 // Конфигурация кэша
 var (
 	CacheMaxItems = getEnvAsInt("CACHE_MAX_ITEMS", 20)
+	CachePolicy   = getEnv("CACHE_POLICY", "lru") // random, lru, lfu, arc
+	// Ограничение кэша по памяти в байтах (0 — ограничение по CACHE_MAX_ITEMS)
+	CacheMaxBytes = int64(getEnvAsInt("CACHE_MAX_BYTES", 0))
+	// Количество независимых сегментов кэша
+	CacheShards = getEnvAsInt("CACHE_SHARDS", 1)
+	// Время жизни записи в кэше (0 — без ограничения)
+	CacheTTL = time.Second * time.Duration(getEnvAsInt("CACHE_TTL", 0))
+	// Продлевать жизнь записи при каждом чтении
+	CacheSlidingTTL = getEnvAsBool("CACHE_SLIDING_TTL", false)
+	// Как часто удалять истёкшие записи
+	CacheJanitorInterval = time.Second * time.Duration(getEnvAsInt("CACHE_JANITOR_INTERVAL", 60))
+	// Сколько помнить, что заказа нет в БД (0 — не помнить), и сколько таких UID'ов хранить
+	CacheNegativeTTL      = time.Second * time.Duration(getEnvAsInt("CACHE_NEGATIVE_TTL", 30))
+	CacheNegativeMaxItems = getEnvAsInt("CACHE_NEGATIVE_MAX_ITEMS", 1000)
+	// Файл снимка кэша для тёплого рестарта (пусто — восстанавливать только из Hash)
+	CacheSnapshotPath = getEnv("CACHE_SNAPSHOT_PATH", "")
+	// Сколько горутин и какими пачками загружают заказы при восстановлении кэша из БД
+	CacheRestoreWorkers = getEnvAsInt("CACHE_RESTORE_WORKERS", 4)
+	CacheRestoreBatch   = getEnvAsInt("CACHE_RESTORE_BATCH", 100)
+	// Слушать уведомления Postgres об изменении заказов другими экземплярами сервиса
+	CacheListenUpdates = getEnvAsBool("CACHE_LISTEN_UPDATES", true)
+	// Класть в кэш заказы, записанные в БД из Kafka
+	CacheWriteThrough = getEnvAsBool("CACHE_WRITE_THROUGH", false)
+)
+
+// Конфигурация второго уровня кэша (Redis или совместимый по протоколу сервер)
+var (
+	RedisAddr      = getEnv("REDIS_ADDR", "") // пусто — второй уровень выключен
+	RedisPassword  = getEnv("REDIS_PASSWORD", "")
+	RedisKeyPrefix = getEnv("REDIS_KEY_PREFIX", "order:")
+	RedisTTL       = time.Second * time.Duration(getEnvAsInt("REDIS_TTL", 600))
+	RedisPoolSize  = getEnvAsInt("REDIS_POOL_SIZE", 10)
+	RedisTimeout   = time.Millisecond * time.Duration(getEnvAsInt("REDIS_TIMEOUT_MS", 500))
 )
 
 // getEnv возвращает значение из переменной окружения или значение по умолчанию
@@ -55,3 +131,51 @@ func getEnvAsInt(name string, defaultValue int) int {
 	}
 	return val
 }
+
+// getEnvAsFloat читаем переменную как float64
+func getEnvAsFloat(name string, defaultValue float64) float64 {
+	valStr := getEnv(name, "")
+	if valStr == "" {
+		return defaultValue
+	}
+	val, err := strconv.ParseFloat(valStr, 64)
+	if err != nil {
+		log.Printf("Ошибка при парсинге %s: %v. Используется значение по умолчанию: %g", name, err, defaultValue)
+		return defaultValue
+	}
+	return val
+}
+
+// getEnvAsFloatMap читаем переменную вида "KEY=1.5,OTHER=2" как map.
+// Неверные пары пропускаются с сообщением в лог
+func getEnvAsFloatMap(name string) map[string]float64 {
+	out := make(map[string]float64)
+	for _, pair := range strings.Split(getEnv(name, ""), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, valStr, ok := strings.Cut(pair, "=")
+		val, err := strconv.ParseFloat(strings.TrimSpace(valStr), 64)
+		if !ok || err != nil {
+			log.Printf("Ошибка при парсинге %s: неверная пара %q, пропускаем", name, pair)
+			continue
+		}
+		out[strings.TrimSpace(key)] = val
+	}
+	return out
+}
+
+// getEnvAsBool читаем переменную как bool
+func getEnvAsBool(name string, defaultValue bool) bool {
+	valStr := getEnv(name, "")
+	if valStr == "" {
+		return defaultValue
+	}
+	val, err := strconv.ParseBool(valStr)
+	if err != nil {
+		log.Printf("Ошибка при парсинге %s: %v. Используется значение по умолчанию: %t", name, err, defaultValue)
+		return defaultValue
+	}
+	return val
+}
@@ -9,158 +9,339 @@ import (
 	"time"
 
 	"project_wb_l0/modules/general"
+	"project_wb_l0/modules/retry"
+	"project_wb_l0/modules/schema"
 
 	"github.com/segmentio/kafka-go"
 )
 
+// Record — провалидированная запись вместе с координатами исходного сообщения в кафке.
+// Kind говорит, какое из полей Order, Status и Cancel заполнено
+type Record struct {
+	Kind      Kind
+	Order     general.Order
+	Status    general.OrderStatus
+	Cancel    general.OrderCancel
+	Topic     string
+	Partition int
+	Offset    int64
+	// Расхождения сумм заказа, которые нужно сохранить для проверки финансистами
+	Mismatches []FinanceMismatch
+}
+
+// Answer — ответ бд на пачку записей.
+// Err — ошибка записи пачки целиком. Если бд затем записывала заказы по одному,
+// Errs содержит результат для каждой записи пачки (nil — записана)
 type Answer struct {
-	Err error
+	Err  error
+	Errs []error
+}
+
+// ErrFor возвращает результат записи i-й записи пачки
+func (a Answer) ErrFor(i int) error {
+	if a.Errs != nil {
+		return a.Errs[i]
+	}
+	return a.Err
 }
 
 type Consumer struct {
-	reader     *kafka.Reader
-	answerBd   chan Answer
-	sendDataBd chan general.Order
+	config       kafka.ReaderConfig
+	reader       *kafka.Reader // пересоздаётся при каждом перезапуске чтения
+	topics       []string
+	handlers     map[string]Handler // топик → обработчик
+	stats        map[string]*topicCounters
+	health       healthState
+	workers      []*worker
+	workerCount  int
+	batchSize    int
+	batchTimeout time.Duration
+	deadLetter   *DeadLetterWriter
+	haltOnRetry  bool
+	rules        *RuleSet
+	finance      *FinanceCheck
+	schema       *schema.Schema
+	strictSchema bool
+	decoders     *Decoders
+	serveFn      func(ctx context.Context, rateLimit time.Duration) error // один запуск чтения (serve); подменяется в тестах
+}
+
+var (
+	// ErrHalted — чтение остановлено, потому что бд так и не приняла пачку
+	ErrHalted = errors.New("чтение из кафки остановлено")
+	// ErrCommit — не удалось закоммитить оффсеты
+	ErrCommit = errors.New("не удалось закоммитить оффсеты")
+	// ErrDeadLetter — не удалось переслать пачку в dead-letter топик, оффсеты не закоммичены
+	ErrDeadLetter = errors.New("не удалось переслать сообщения в dead-letter топик")
+)
+
+// commitPolicy — повтор коммита оффсетов
+var commitPolicy = retry.Policy{Attempts: 5, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// commitTimeout ограничивает коммит, который доделывается и при остановке сервиса
+const commitTimeout = 30 * time.Second
+
+// Option — необязательная настройка консюмера
+type Option func(*Consumer)
+
+// WithDeadLetter пересылает сообщения, не прошедшие разбор, валидацию или запись в бд,
+// в dead-letter топик вместо того, чтобы только писать их в лог
+func WithDeadLetter(w *DeadLetterWriter) Option {
+	return func(c *Consumer) { c.deadLetter = w }
+}
+
+// WithRules задаёт правила валидации заказов (по умолчанию DefaultRules)
+func WithRules(rs *RuleSet) Option {
+	return func(c *Consumer) { c.rules = rs }
 }
 
-// Канал для чтения информации о заказе
-func (c *Consumer) Send() <-chan general.Order {
-	return c.sendDataBd
+// WithDecoders задаёт декодеры форматов сообщений (по умолчанию JSON и Protobuf без реестра;
+// Avro и реестр подключаются через NewDecoders)
+func WithDecoders(d *Decoders) Option {
+	return func(c *Consumer) { c.decoders = d }
 }
 
-// Канал для принятия ответом
-func (c *Consumer) RecieveAnswer() chan<- Answer {
-	return c.answerBd
+// WithSchema проверяет каждое сообщение по JSON Schema заказа до правил валидации.
+// В строгом режиме (strict) сообщения с полями, которых нет в схеме, отклоняются
+func WithSchema(s *schema.Schema, strict bool) Option {
+	return func(c *Consumer) {
+		c.schema = s
+		c.strictSchema = strict
+	}
+}
+
+// WithFinanceCheck сверяет суммы заказов; расхождения передаются в бд вместе с заказом
+func WithFinanceCheck(fc *FinanceCheck) Option {
+	return func(c *Consumer) { c.finance = fc }
+}
+
+// WithWorkers задаёт число параллельных обработчиков (по умолчанию 1).
+// Сообщения одной партиции всегда попадают к одному обработчику и обрабатываются по порядку,
+// разные партиции обрабатываются параллельно и коммитятся независимо
+func WithWorkers(n int) Option {
+	return func(c *Consumer) { c.workerCount = max(1, n) }
+}
+
+// WithHaltOnRetriesExhausted останавливает чтение, если бд исчерпала попытки записи
+// на временной ошибке. Пачка не коммитится, а супервизор перезапускает чтение с задержкой,
+// и пачка читается заново. Без этой опции такие сообщения уходят в dead-letter топик,
+// как и постоянные ошибки записи. Без dead-letter топика чтение останавливается всегда:
+// иначе заказ был бы закоммичен и потерян
+func WithHaltOnRetriesExhausted() Option {
+	return func(c *Consumer) { c.haltOnRetry = true }
+}
+
+// Workers возвращает обработчиков консюмера — каждого нужно подключить к бд
+func (c *Consumer) Workers() []Registration {
+	regs := make([]Registration, len(c.workers))
+	for i, w := range c.workers {
+		regs[i] = w
+	}
+	return regs
 }
 
 type Registration interface {
-	Send() <-chan general.Order
+	Send() <-chan []Record
 	RecieveAnswer() chan<- Answer
 }
 
-// инициализируем консюмер и зупаскаем чтение из кафки и отправки дальше по каналу sendDataBd через метод Send
-func InitConsumer(ctx context.Context, brokers []string, topic, groupID string, checkFrequency int) Registration {
+// инициализируем консюмер и зупаскаем чтение из кафки и отправки дальше по каналам обработчиков (см. Workers).
+// topic — топик заказов; другие топики со своими обработчиками подключаются через WithTopic
+// и читаются в той же группе groupID.
+// Сообщения раскладываются по обработчикам по номеру партиции и копятся в пачку,
+// пока их не наберётся batchSize или не пройдёт batchTimeout с первого сообщения пачки;
+// пачка отправляется в бд целиком, а следующая пачка этого обработчика
+// собирается только после ответа бд на предыдущую.
+// rateLimit задаёт минимальный интервал между пачками (0 — без ограничения).
+// Чтение работает под супервизором, который перезапускает его после падения
+func InitConsumer(ctx context.Context, brokers []string, topic, groupID string,
+	rateLimit time.Duration, batchSize int, batchTimeout time.Duration, opts ...Option) *Consumer {
 	c := &Consumer{
-		reader: kafka.NewReader(kafka.ReaderConfig{
+		config: kafka.ReaderConfig{
 			Brokers:        brokers,
-			Topic:          topic,
 			GroupID:        groupID,
 			CommitInterval: 0, // Отключаем автоматический коммит
-		}),
-		sendDataBd: make(chan general.Order),
-		answerBd:   make(chan Answer),
+		},
+		topics:       []string{topic},
+		handlers:     make(map[string]Handler),
+		stats:        make(map[string]*topicCounters),
+		workerCount:  1,
+		rules:        DefaultRules(),
+		batchSize:    max(1, batchSize),
+		batchTimeout: batchTimeout,
+	}
+	c.handlers[topic] = HandlerFunc(c.handleOrder)
+	c.serveFn = c.serve
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.config.GroupTopics = c.topics
+	for _, t := range c.topics {
+		c.stats[t] = &topicCounters{}
+	}
+	if c.decoders == nil {
+		c.decoders = newDecoders(nil)
+	}
+	for range c.workerCount {
+		c.workers = append(c.workers, newWorker())
 	}
-	go c.serve(ctx, checkFrequency)
+	c.health.h = Health{Topics: c.topics, State: StateStarting, Since: time.Now()}
+	go c.supervise(ctx, rateLimit)
 	return c
 }
 
-// функция для чтение данных из кафки
-// валидации ей по структуру Order, если данные не подходят под валидацию - алерт и коммит ( чтобы читать дальше)
+// функция для обработки пачки данных из кафки
+// разбора и валидации каждого сообщения обработчиком его топика, если данные не подходят под валидацию - алерт (и коммит вместе с пачкой, чтобы читать дальше)
 // отправки на бд
-// после получения ответа от бд - коммитив
-func (c *Consumer) fetch(ctx context.Context) {
-	msg, err := c.reader.ReadMessage(ctx)
-	if err != nil {
-		log.Printf("Проблема с чтение данных из кафки: %v\n", err)
-		return
-	}
-	validatedData := validateOrder(msg.Value)
-	if validatedData.Err != nil {
-		//отправляем алерт, что что-то не так
-		log.Printf("Проблемы с валидацией данных: %v\n в topic=%s, partition=%d, offset=%d \n", validatedData.Err,
-			msg.Topic,
-			msg.Partition,
-			msg.Offset)
-		// Коммитим оффсет вручную после обработки
-		err = c.reader.CommitMessages(ctx, msg)
+// пересылки всех отказов пачки в dead-letter топик одним запросом (не переслали - не коммитим, пачка будет прочитана снова)
+// после получения ответа от бд - коммитив наибольший оффсет каждой партиции
+// Пока бд не ответила, обработчик не берёт следующую пачку — это и есть обратное давление
+func (c *Consumer) process(ctx context.Context, w *worker, msgs []kafka.Message) error {
+	batch := make([]Record, 0, len(msgs))
+	sources := make([]kafka.Message, 0, len(msgs))
+	var dead []DeadLetter
+	for _, msg := range msgs {
+		stats := c.stats[msg.Topic]
+		stats.received.Add(1)
+		rec, err := c.handlers[msg.Topic].Handle(ctx, msg)
+		if errors.Is(err, ErrRegistryUnavailable) {
+			// Сообщение не испорчено: не коммитим, супервизор перечитает пачку после паузы
+			return err
+		}
 		if err != nil {
-			panic(err)
+			stats.rejected.Add(1)
+			//отправляем алерт, что что-то не так
+			log.Printf("Проблемы с валидацией данных: %v\n в topic=%s, partition=%d, offset=%d \n", err,
+				msg.Topic,
+				msg.Partition,
+				msg.Offset)
+			dead = append(dead, DeadLetter{Stage: stageOf(err), Reason: err, Msg: msg})
+			continue
 		}
-		return
-	}
-
-	for {
-		select {
-		case c.sendDataBd <- validatedData.Order:
-			//логика после отправки данных в бд
-			for {
-				select {
-				case answer := <-c.answerBd:
-					if answer.Err != nil {
-						log.Printf("Проблемы с записью заказа в базу данных: %v\n", answer.Err)
-					}
-
-					// Коммитим оффсет вручную после обработки
-					err = c.reader.CommitMessages(context.Background(), msg)
-					if err != nil {
-						panic(err)
-					}
-					//типа конец, отправили на бд  и что-то получили теперь можно и выходить из попыток
-					log.Printf("Данные успешно записаны в бд из topic=%s, partition=%d, offset=%d \n",
-						msg.Topic,
-						msg.Partition,
-						msg.Offset)
-					return
-				case <-ctx.Done():
-					return
+		rec.Topic = msg.Topic
+		rec.Partition = msg.Partition
+		rec.Offset = msg.Offset
+		batch = append(batch, rec)
+		sources = append(sources, msg)
+	}
+
+	if len(batch) > 0 {
+		answer, ok := w.write(ctx, batch)
+		if !ok {
+			//надо возвращаться, пачка не закоммичена и будет прочитана снова
+			return nil
+		}
+		if c.haltOnRetry || c.deadLetter == nil {
+			for i := range batch {
+				if err := answer.ErrFor(i); errors.Is(err, retry.ErrExhausted) {
+					// Не коммитим: сообщения пачки должны быть прочитаны снова
+					return fmt.Errorf("%w: %w", ErrHalted, err)
 				}
 			}
-		case <-ctx.Done():
-			//надо возвращаться
-			return
 		}
+		for i, rec := range batch {
+			if err := answer.ErrFor(i); err != nil {
+				c.stats[rec.Topic].failed.Add(1)
+				log.Printf("Проблемы с записью заказа в базу данных: %v\n в topic=%s, partition=%d, offset=%d \n", err,
+					rec.Topic,
+					rec.Partition,
+					rec.Offset)
+				dead = append(dead, DeadLetter{Stage: StagePersist, Reason: err, Msg: sources[i]})
+				continue
+			}
+			c.stats[rec.Topic].written.Add(1)
+		}
+		log.Printf("Пачка из %d записей обработана бд\n", len(batch))
 	}
 
+	if err := c.sendDeadLetters(ctx, dead); err != nil {
+		return err
+	}
+
+	// Коммитим оффсеты вручную после обработки всей пачки
+	return c.commit(lastPerPartition(msgs))
 }
 
-func (c *Consumer) serve(ctx context.Context, checkFrequency int) {
-	clock := time.NewTicker(time.Duration(checkFrequency) * time.Second)
-	for {
-		select {
-		case <-clock.C:
-			c.fetch(ctx)
-		case <-ctx.Done():
-			close(c.sendDataBd)
-			close(c.answerBd)
-			return
+// commit коммитит оффсеты, повторяя попытки. Пачка уже записана,
+// поэтому коммит не прерывается остановкой сервиса, а ограничен commitTimeout
+func (c *Consumer) commit(msgs []kafka.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+	defer cancel()
+	err := retry.Do(ctx, commitPolicy, func(error) bool { return true }, func() error {
+		err := c.reader.CommitMessages(ctx, msgs...)
+		if err != nil {
+			log.Printf("Ошибка коммита оффсетов: %v\n", err)
 		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCommit, err)
 	}
+	return nil
 }
 
-func ValidateTrackNumbers(order general.Order) error {
-	if order.TrackNumber == "" {
-		return errors.New("track_number в заказе не задан")
+// sendDeadLetters пересылает отказы пачки в dead-letter топик, если он настроен.
+// Без топика сообщения остаются только в логе. Если переслать не удалось,
+// возвращается ErrDeadLetter: пачку нельзя коммитить, иначе сообщения потеряются
+func (c *Consumer) sendDeadLetters(ctx context.Context, dead []DeadLetter) error {
+	if c.deadLetter == nil || len(dead) == 0 {
+		return nil
+	}
+	if err := c.deadLetter.Send(ctx, dead...); err != nil {
+		return fmt.Errorf("%w (%d сообщений): %w", ErrDeadLetter, len(dead), err)
 	}
+	return nil
+}
 
-	for i, item := range order.Items {
-		if item.TrackNumber != order.TrackNumber {
-			return fmt.Errorf("item[%d] имеет неверный track_number: %s вместо %s",
-				i, item.TrackNumber, order.TrackNumber)
+// lastPerPartition оставляет из пачки по сообщению с наибольшим оффсетом в каждой партиции:
+// коммит такого сообщения подтверждает и все предыдущие
+func lastPerPartition(msgs []kafka.Message) []kafka.Message {
+	last := make(map[string]int)
+	var out []kafka.Message
+	for _, msg := range msgs {
+		key := fmt.Sprintf("%s/%d", msg.Topic, msg.Partition)
+		i, ok := last[key]
+		if !ok {
+			last[key] = len(out)
+			out = append(out, msg)
+			continue
+		}
+		if msg.Offset > out[i].Offset {
+			out[i] = msg
 		}
 	}
-
-	return nil
+	return out
 }
 
-func validateOrder(result []byte) general.ValidateResult {
+// validateOrder декодирует сообщение по его формату, проверяет по схеме (если задана) и правилами консюмера.
+// Нарушения с серьёзностью warn пишутся в лог и не мешают записи заказа
+func (c *Consumer) validateOrder(ctx context.Context, msg kafka.Message) general.ValidateResult {
 	order := general.Order{}
-	err := json.Unmarshal(result, &order)
-	if err != nil {
+	result, err := c.decoders.Decode(ctx, msg)
+	if errors.Is(err, ErrRegistryUnavailable) {
 		return general.ValidateResult{Order: order, Err: err}
 	}
-	if order.OrderUID == "" {
-		return general.ValidateResult{Order: order, Err: errors.New("поле ID не заполнено")}
+	if err != nil {
+		return general.ValidateResult{Order: order, Err: fmt.Errorf("%w: %v", ErrDecode, err)}
 	}
-	if order.Delivery.Name == "" {
-		return general.ValidateResult{Order: order, Err: errors.New("поле name  отнощения Delivery не заполнено")}
+	err = json.Unmarshal(result, &order)
+	if err != nil {
+		return general.ValidateResult{Order: order, Err: fmt.Errorf("%w: %v", ErrDecode, err)}
 	}
-	if order.Payment.Transaction == "" {
-		return general.ValidateResult{Order: order, Err: errors.New("поле transaction отношения Payment не заполнено")}
+	if c.schema != nil {
+		if err := c.schema.Validate(result, c.strictSchema); err != nil {
+			return general.ValidateResult{Order: order, Err: err}
+		}
 	}
-	err = ValidateTrackNumbers(order)
-	if err != nil {
-		return general.ValidateResult{Order: order, Err: err}
+	verr := c.rules.Check(order)
+	if verr == nil {
+		return general.ValidateResult{Order: order, Err: nil}
+	}
+	for _, w := range verr.Warnings() {
+		log.Printf("Предупреждение валидации заказа %s: %s\n", order.OrderUID, w)
+	}
+	if verr.Rejected() {
+		return general.ValidateResult{Order: order, Err: verr}
 	}
 	return general.ValidateResult{Order: order, Err: nil}
-
 }
@@ -0,0 +1,105 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Stage — этап обработки, на котором сообщение не прошло
+type Stage string
+
+const (
+	StageDecode   Stage = "decode"   // сообщение не разобралось в Order
+	StageValidate Stage = "validate" // заказ не прошёл валидацию
+	StagePersist  Stage = "persist"  // заказ не записался в бд
+)
+
+// Заголовки, которые добавляются к сообщению в dead-letter топике
+const (
+	HeaderDLQReason          = "dlq-reason"
+	HeaderDLQStage           = "dlq-stage"
+	HeaderDLQSourceTopic     = "dlq-source-topic"
+	HeaderDLQSourcePartition = "dlq-source-partition"
+	HeaderDLQSourceOffset    = "dlq-source-offset"
+	HeaderDLQTimestamp       = "dlq-timestamp"
+)
+
+// ErrDecode — сообщение не удалось разобрать
+var ErrDecode = errors.New("не удалось разобрать сообщение")
+
+// DeadLetterWriter пересылает необработанные сообщения в отдельный топик,
+// чтобы их можно было разобрать и переотправить позже
+type DeadLetterWriter struct {
+	writer *kafka.Writer
+}
+
+// DeadLetter — сообщение, которое нужно переслать, с этапом и причиной отказа
+type DeadLetter struct {
+	Stage  Stage
+	Reason error
+	Msg    kafka.Message
+}
+
+// deadLetterBatchTimeout — сколько писатель ждёт добора пачки.
+// Send отправляет всю пачку одним вызовом, поэтому долго ждать незачем
+const deadLetterBatchTimeout = 10 * time.Millisecond
+
+// NewDeadLetterWriter создаёт писателя в dead-letter топик topic
+func NewDeadLetterWriter(brokers []string, topic string) *DeadLetterWriter {
+	return &DeadLetterWriter{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			BatchTimeout:           deadLetterBatchTimeout,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+// Send пересылает исходные сообщения без изменений одним запросом, добавляя
+// к заголовкам каждого причину и этап отказа, координаты исходного сообщения и время отказа
+func (w *DeadLetterWriter) Send(ctx context.Context, letters ...DeadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	out := make([]kafka.Message, len(letters))
+	for i, l := range letters {
+		msg := l.Msg
+		headers := make([]kafka.Header, 0, len(msg.Headers)+6)
+		headers = append(headers, msg.Headers...)
+		headers = append(headers,
+			kafka.Header{Key: HeaderDLQReason, Value: []byte(l.Reason.Error())},
+			kafka.Header{Key: HeaderDLQStage, Value: []byte(l.Stage)},
+			kafka.Header{Key: HeaderDLQSourceTopic, Value: []byte(msg.Topic)},
+			kafka.Header{Key: HeaderDLQSourcePartition, Value: []byte(strconv.Itoa(msg.Partition))},
+			kafka.Header{Key: HeaderDLQSourceOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+			kafka.Header{Key: HeaderDLQTimestamp, Value: []byte(now)},
+		)
+		out[i] = kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers}
+	}
+	if err := w.writer.WriteMessages(ctx, out...); err != nil {
+		return fmt.Errorf("ошибка отправки в dead-letter топик %s: %w", w.writer.Topic, err)
+	}
+	return nil
+}
+
+// Close дожидается отправки буферизованных сообщений и закрывает писателя
+func (w *DeadLetterWriter) Close() error {
+	return w.writer.Close()
+}
+
+// stageOf определяет этап, на котором сообщение не прошло проверку
+func stageOf(err error) Stage {
+	if errors.Is(err, ErrDecode) {
+		return StageDecode
+	}
+	return StageValidate
+}
@@ -0,0 +1,87 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/retry"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// unreachableDeadLetter создаёт писателя в топик на адресе, где никто не слушает
+func unreachableDeadLetter(t *testing.T) *DeadLetterWriter {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	w := NewDeadLetterWriter([]string{addr}, "orders-dlq")
+	w.writer.MaxAttempts = 1
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+func TestFailedDeadLetterSkipsCommit(t *testing.T) {
+	const topic = "order-info"
+	reject := HandlerFunc(func(_ context.Context, msg kafka.Message) (Record, error) {
+		return Record{}, fmt.Errorf("%w: плохое сообщение %d", ErrDecode, msg.Offset)
+	})
+	// reader не задан: попытка коммита закончилась бы паникой
+	c := &Consumer{
+		handlers:   map[string]Handler{topic: reject},
+		stats:      map[string]*topicCounters{topic: {}},
+		deadLetter: unreachableDeadLetter(t),
+	}
+	msgs := []kafka.Message{
+		{Topic: topic, Partition: 0, Offset: 1, Value: []byte("{")},
+		{Topic: topic, Partition: 0, Offset: 2, Value: []byte("[")},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := c.process(ctx, newWorker(), msgs)
+	if !errors.Is(err, ErrDeadLetter) {
+		t.Fatalf("ожидалась ErrDeadLetter, получено %v", err)
+	}
+	if n := c.stats[topic].rejected.Load(); n != 2 {
+		t.Fatalf("отклонено %d сообщений, ожидалось 2", n)
+	}
+}
+
+func TestSendDeadLettersWithoutTopic(t *testing.T) {
+	c := &Consumer{}
+	err := c.sendDeadLetters(context.Background(), []DeadLetter{{Stage: StageDecode, Reason: ErrDecode}})
+	if err != nil {
+		t.Fatalf("без dead-letter топика отказы только логируются, получено %v", err)
+	}
+}
+
+func TestExhaustedRetriesWithoutDeadLetterHalt(t *testing.T) {
+	const topic = "order-info"
+	accept := HandlerFunc(func(_ context.Context, msg kafka.Message) (Record, error) {
+		return Record{Kind: KindOrder}, nil
+	})
+	// Ни halt, ни dead-letter топика: коммит потерял бы заказ, reader не задан
+	c := &Consumer{
+		handlers: map[string]Handler{topic: accept},
+		stats:    map[string]*topicCounters{topic: {}},
+	}
+	w := newWorker()
+	go func() {
+		<-w.Send()
+		w.RecieveAnswer() <- Answer{Err: fmt.Errorf("%w: бд недоступна", retry.ErrExhausted)}
+	}()
+
+	err := c.process(context.Background(), w, []kafka.Message{{Topic: topic, Offset: 1}})
+	if !errors.Is(err, ErrHalted) || !errors.Is(err, retry.ErrExhausted) {
+		t.Fatalf("ожидалась остановка без коммита, получено %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package consumer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+
+	"project_wb_l0/modules/avro"
+	"project_wb_l0/modules/general"
+	"project_wb_l0/modules/registry"
+	"project_wb_l0/modules/schema"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// HeaderContentType — заголовок Kafka с форматом сообщения
+const HeaderContentType = "content-type"
+
+// Форматы сообщений
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+)
+
+// ErrRegistryUnavailable — схему не удалось получить из реестра (реестр недоступен или отвечает ошибкой).
+// Такое сообщение не испорчено: пачка не коммитится и будет прочитана снова
+var ErrRegistryUnavailable = errors.New("реестр схем недоступен")
+
+// Decoder переводит полезную нагрузку сообщения в JSON заказа.
+// Дальше сообщение проверяется схемой и правилами одинаково для всех форматов
+type Decoder interface {
+	Decode(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// DecoderFunc — функция, реализующая Decoder
+type DecoderFunc func(ctx context.Context, payload []byte) ([]byte, error)
+
+func (f DecoderFunc) Decode(ctx context.Context, payload []byte) ([]byte, error) {
+	return f(ctx, payload)
+}
+
+// Decoders выбирает декодер по заголовку content-type. Сообщения в формате
+// реестра схем (magic byte + номер схемы) декодируются по схеме из реестра
+type Decoders struct {
+	mu       sync.RWMutex
+	byType   map[string]Decoder
+	registry *registry.Client
+	avro     sync.Map // номер схемы → *avro.Schema
+}
+
+// NewDecoders создаёт набор декодеров с JSON, Protobuf и Avro.
+// reg может быть nil — тогда сообщения со ссылкой на реестр не принимаются
+func NewDecoders(reg *registry.Client) (*Decoders, error) {
+	orderAvro, err := schema.OrderAvro()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора Avro-схемы заказа: %w", err)
+	}
+	d := newDecoders(reg)
+	avroDec := DecoderFunc(func(_ context.Context, payload []byte) ([]byte, error) {
+		return schema.AvroToJSON(orderAvro, payload)
+	})
+	d.Register(ContentTypeAvro, avroDec)
+	d.Register("avro/binary", avroDec)
+	d.Register("application/vnd.apache.avro+binary", avroDec)
+	return d, nil
+}
+
+// newDecoders создаёт набор декодеров с JSON и Protobuf
+func newDecoders(reg *registry.Client) *Decoders {
+	d := &Decoders{byType: make(map[string]Decoder), registry: reg}
+	d.Register(ContentTypeJSON, DecoderFunc(decodeJSON))
+	protobuf := DecoderFunc(decodeProtobuf)
+	d.Register(ContentTypeProtobuf, protobuf)
+	d.Register("application/protobuf", protobuf)
+	d.Register("application/vnd.google.protobuf", protobuf)
+	return d
+}
+
+// Register подключает декодер для формата contentType (заменяет существующий)
+func (d *Decoders) Register(contentType string, dec Decoder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.byType[strings.ToLower(contentType)] = dec
+}
+
+// Decode переводит сообщение в JSON заказа.
+// Без заголовка content-type сообщение в формате реестра декодируется по схеме из реестра,
+// остальные считаются JSON. С заголовком используется декодер этого формата; сообщение
+// в формате реестра идёт в реестр, только если он настроен (JSON с нулевого байта
+// не начинается, а у Avro без реестра так может начинаться пустая первая строка)
+func (d *Decoders) Decode(ctx context.Context, msg kafka.Message) ([]byte, error) {
+	contentType, explicit := contentTypeOf(msg)
+	if registry.IsFramed(msg.Value) && (!explicit || d.registry != nil) {
+		return d.decodeFramed(ctx, msg.Value)
+	}
+
+	d.mu.RLock()
+	dec, ok := d.byType[contentType]
+	d.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("неизвестный формат сообщения %q", contentType)
+	}
+	return dec.Decode(ctx, msg.Value)
+}
+
+// decodeFramed декодирует сообщение по схеме из реестра
+func (d *Decoders) decodeFramed(ctx context.Context, data []byte) ([]byte, error) {
+	if d.registry == nil {
+		return nil, fmt.Errorf("сообщение ссылается на реестр схем, но реестр не настроен")
+	}
+	id, payload, err := registry.Unframe(data)
+	if err != nil {
+		return nil, err
+	}
+	s, err := d.registry.SchemaByID(ctx, id)
+	if err != nil {
+		if registry.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrRegistryUnavailable, err)
+	}
+	switch s.SchemaType() {
+	case registry.TypeAvro:
+		writer, err := d.avroSchema(id, s)
+		if err != nil {
+			return nil, err
+		}
+		return schema.AvroToJSON(writer, payload)
+	case registry.TypeProtobuf:
+		payload, err := skipMessageIndexes(payload)
+		if err != nil {
+			return nil, err
+		}
+		return decodeProtobuf(ctx, payload)
+	case registry.TypeJSON:
+		return payload, nil
+	}
+	return nil, fmt.Errorf("неизвестный тип схемы %q (номер %d)", s.Type, id)
+}
+
+// avroSchema разбирает Avro-схему из реестра один раз на номер
+func (d *Decoders) avroSchema(id int, s registry.Schema) (*avro.Schema, error) {
+	if cached, ok := d.avro.Load(id); ok {
+		return cached.(*avro.Schema), nil
+	}
+	parsed, err := avro.Parse(s.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("схема %d: %w", id, err)
+	}
+	d.avro.Store(id, parsed)
+	return parsed, nil
+}
+
+// skipMessageIndexes пропускает номера сообщения внутри .proto, которые Confluent
+// пишет после номера схемы: количество и сами номера в zigzag varint
+func skipMessageIndexes(payload []byte) ([]byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, fmt.Errorf("неверные номера сообщения protobuf")
+	}
+	payload = payload[n:]
+	for ; count > 0; count-- {
+		_, n := binary.Varint(payload)
+		if n <= 0 {
+			return nil, fmt.Errorf("неверные номера сообщения protobuf")
+		}
+		payload = payload[n:]
+	}
+	return payload, nil
+}
+
+// contentTypeOf возвращает формат из заголовка content-type (без параметров)
+// и признак того, что заголовок был
+func contentTypeOf(msg kafka.Message) (string, bool) {
+	for _, h := range msg.Headers {
+		if strings.EqualFold(h.Key, HeaderContentType) {
+			if mt, _, err := mime.ParseMediaType(string(h.Value)); err == nil {
+				return mt, true
+			}
+			return strings.ToLower(strings.TrimSpace(string(h.Value))), true
+		}
+	}
+	return ContentTypeJSON, false
+}
+
+func decodeJSON(_ context.Context, payload []byte) ([]byte, error) {
+	return payload, nil
+}
+
+func decodeProtobuf(_ context.Context, payload []byte) ([]byte, error) {
+	order, err := schema.DecodeOrderProto(payload)
+	if err != nil {
+		return nil, err
+	}
+	// В protobuf пустой список не отличить от отсутствующего, а схема ждёт массив
+	if order.Items == nil {
+		order.Items = []general.Item{}
+	}
+	return json.Marshal(order)
+}
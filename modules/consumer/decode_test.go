@@ -0,0 +1,188 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/general"
+	"project_wb_l0/modules/registry"
+	"project_wb_l0/modules/schema"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func sampleOrder() general.Order {
+	return general.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Delivery:    general.Delivery{Name: "Test Testov", Phone: "+9720000000", City: "Kiryat Mozkin"},
+		Payment: general.Payment{
+			Transaction: "b563feb7b2b84b6test", Currency: "USD", Provider: "wbpay",
+			Amount: 1817, PaymentDT: time.Unix(1637907727, 0).UTC(), DeliveryCost: 1500, GoodsTotal: 317,
+		},
+		Items: []general.Item{{
+			ChrtID: "9934930", TrackNumber: "WBILMTESTTRACK", Price: 453, Name: "Mascaras",
+			Sale: 30, TotalPrice: 317, NmID: "2389212", Brand: "Vivienne Sabo", Status: 202,
+		}},
+		Locale:      "en",
+		CustomerID:  "test",
+		DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 123456000, time.UTC),
+	}
+}
+
+func mustDecoders(t *testing.T) *Decoders {
+	t.Helper()
+	d, err := NewDecoders(nil)
+	if err != nil {
+		t.Fatalf("NewDecoders: %v", err)
+	}
+	return d
+}
+
+func withContentType(value []byte, contentType string) kafka.Message {
+	return kafka.Message{Value: value, Headers: []kafka.Header{{Key: HeaderContentType, Value: []byte(contentType)}}}
+}
+
+func TestDecodeProtobufWithoutItemsPassesSchema(t *testing.T) {
+	order := sampleOrder()
+	order.Items = nil
+	body, err := mustDecoders(t).Decode(context.Background(),
+		withContentType(schema.EncodeOrderProto(order), ContentTypeProtobuf))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	s, err := schema.Order()
+	if err != nil {
+		t.Fatalf("schema.Order: %v", err)
+	}
+	if err := s.Validate(body, false); err != nil {
+		t.Fatalf("заказ без товаров не прошёл схему: %v\n%s", err, body)
+	}
+}
+
+func startRegistry(t *testing.T) (*registry.Server, *registry.Client) {
+	t.Helper()
+	srv, err := registry.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("registry.NewServer: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv, registry.NewClient(srv.URL(), time.Second)
+}
+
+func TestDecodeFramedThroughRegistry(t *testing.T) {
+	_, reg := startRegistry(t)
+	ctx := context.Background()
+	avroID, err := reg.Register(ctx, "order-avro", registry.Schema{Type: registry.TypeAvro, Schema: schema.OrderAvsc()})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	protoID, err := reg.Register(ctx, "order-protobuf", registry.Schema{Type: registry.TypeProtobuf, Schema: schema.OrderProto()})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	d, err := NewDecoders(reg)
+	if err != nil {
+		t.Fatalf("NewDecoders: %v", err)
+	}
+
+	order := sampleOrder()
+	writer, err := schema.OrderAvro()
+	if err != nil {
+		t.Fatalf("OrderAvro: %v", err)
+	}
+	avroPayload, err := schema.EncodeOrderAvro(writer, order)
+	if err != nil {
+		t.Fatalf("EncodeOrderAvro: %v", err)
+	}
+	// Protobuf в формате Confluent: после номера схемы идут номера сообщения, [0] — первое
+	protoPayload := append([]byte{0}, schema.EncodeOrderProto(order)...)
+
+	tests := map[string]kafka.Message{
+		"avro без заголовка":     {Value: registry.Frame(avroID, avroPayload)},
+		"avro с заголовком":      withContentType(registry.Frame(avroID, avroPayload), ContentTypeAvro),
+		"protobuf без заголовка": {Value: registry.Frame(protoID, protoPayload)},
+	}
+	for name, msg := range tests {
+		t.Run(name, func(t *testing.T) {
+			body, err := d.Decode(ctx, msg)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			var got general.Order
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(got, order) {
+				t.Fatalf("получено %+v\nожидалось %+v", got, order)
+			}
+		})
+	}
+}
+
+func TestDecodeFramedUnknownSchema(t *testing.T) {
+	_, reg := startRegistry(t)
+	d, err := NewDecoders(reg)
+	if err != nil {
+		t.Fatalf("NewDecoders: %v", err)
+	}
+	_, err = d.Decode(context.Background(), kafka.Message{Value: registry.Frame(7, []byte{1})})
+	if err == nil || errors.Is(err, ErrRegistryUnavailable) {
+		t.Fatalf("несуществующая схема: ожидалась ошибка разбора, получено %v", err)
+	}
+}
+
+func TestRegistryOutageIsNotDecodeError(t *testing.T) {
+	srv, reg := startRegistry(t)
+	srv.Close()
+	d, err := NewDecoders(reg)
+	if err != nil {
+		t.Fatalf("NewDecoders: %v", err)
+	}
+	c := &Consumer{decoders: d, rules: DefaultRules()}
+	res := c.validateOrder(context.Background(), kafka.Message{Value: registry.Frame(1, []byte{1})})
+	if !errors.Is(res.Err, ErrRegistryUnavailable) {
+		t.Fatalf("ожидалась ErrRegistryUnavailable, получено %v", res.Err)
+	}
+	if errors.Is(res.Err, ErrDecode) {
+		t.Fatalf("недоступность реестра не должна уходить в dead-letter как ошибка разбора: %v", res.Err)
+	}
+}
+
+func TestDecodeByContentType(t *testing.T) {
+	d := mustDecoders(t)
+	ctx := context.Background()
+	order := sampleOrder()
+	jsonBody, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	tests := map[string]kafka.Message{
+		"json без заголовка": {Value: jsonBody},
+		"json с параметрами": withContentType(jsonBody, "application/json; charset=utf-8"),
+		"protobuf":           withContentType(schema.EncodeOrderProto(order), ContentTypeProtobuf),
+	}
+	for name, msg := range tests {
+		t.Run(name, func(t *testing.T) {
+			body, err := d.Decode(ctx, msg)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			var got general.Order
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(got, order) {
+				t.Fatalf("получено %+v\nожидалось %+v", got, order)
+			}
+		})
+	}
+	if _, err := d.Decode(ctx, withContentType([]byte("<order/>"), "text/xml")); err == nil {
+		t.Fatal("неизвестный формат: ожидалась ошибка")
+	}
+}
@@ -0,0 +1,87 @@
+package consumer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"project_wb_l0/modules/general"
+)
+
+// Проверки финансовой согласованности заказа
+const (
+	CheckAmount     = "amount"      // amount = goods_total + delivery_cost + custom_fee
+	CheckGoodsTotal = "goods_total" // goods_total = сумма total_price товаров
+	CheckItemTotal  = "item_total"  // total_price = price со скидкой sale%
+)
+
+// FinanceMismatch — расхождение сумм в заказе больше допустимой погрешности
+type FinanceMismatch struct {
+	Check     string  `json:"check"`
+	Path      string  `json:"path"`
+	Currency  string  `json:"currency"`
+	Expected  float64 `json:"expected"`
+	Actual    float64 `json:"actual"`
+	Tolerance float64 `json:"tolerance"`
+}
+
+func (m FinanceMismatch) String() string {
+	return fmt.Sprintf("%s: %s ожидалось %g, получено %g %s (допуск %g)",
+		m.Check, m.Path, m.Expected, m.Actual, m.Currency, m.Tolerance)
+}
+
+// FinanceCheck сверяет суммы заказа с погрешностью округления, своей для каждой валюты.
+// Расхождения не отклоняют заказ, а записываются для проверки финансистами
+type FinanceCheck struct {
+	defaultTolerance float64
+	tolerances       map[string]float64 // валюта в верхнем регистре → погрешность
+}
+
+// NewFinanceCheck создаёт проверку. Для валют, которых нет в tolerances,
+// используется defaultTolerance
+func NewFinanceCheck(defaultTolerance float64, tolerances map[string]float64) *FinanceCheck {
+	fc := &FinanceCheck{defaultTolerance: defaultTolerance, tolerances: make(map[string]float64, len(tolerances))}
+	for cur, tol := range tolerances {
+		fc.tolerances[strings.ToUpper(cur)] = tol
+	}
+	return fc
+}
+
+// Tolerance возвращает допустимую погрешность для валюты
+func (fc *FinanceCheck) Tolerance(currency string) float64 {
+	if tol, ok := fc.tolerances[strings.ToUpper(currency)]; ok {
+		return tol
+	}
+	return fc.defaultTolerance
+}
+
+// Check возвращает все расхождения сумм в заказе (nil — суммы сходятся)
+func (fc *FinanceCheck) Check(order general.Order) []FinanceMismatch {
+	p := order.Payment
+	tol := fc.Tolerance(p.Currency)
+	var out []FinanceMismatch
+	add := func(check, path string, expected, actual float64) {
+		// Небольшой запас на погрешность представления float64
+		if math.Abs(expected-actual) > tol+1e-9 {
+			out = append(out, FinanceMismatch{
+				Check:     check,
+				Path:      path,
+				Currency:  p.Currency,
+				Expected:  expected,
+				Actual:    actual,
+				Tolerance: tol,
+			})
+		}
+	}
+
+	add(CheckAmount, "payment.amount", p.GoodsTotal+p.DeliveryCost+p.CustomFee, p.Amount)
+
+	var goods float64
+	for i, item := range order.Items {
+		goods += item.TotalPrice
+		expected := item.Price * float64(100-item.Sale) / 100
+		add(CheckItemTotal, fmt.Sprintf("items[%d].total_price", i), expected, item.TotalPrice)
+	}
+	add(CheckGoodsTotal, "payment.goods_total", goods, p.GoodsTotal)
+	return out
+}
@@ -0,0 +1,110 @@
+package consumer
+
+import (
+	"reflect"
+	"testing"
+
+	"project_wb_l0/modules/general"
+)
+
+func TestFinanceTolerance(t *testing.T) {
+	fc := NewFinanceCheck(0.01, map[string]float64{"jpy": 1, "KWD": 0.001})
+	tests := []struct {
+		currency string
+		want     float64
+	}{
+		{"JPY", 1},
+		{"jpy", 1},
+		{"Kwd", 0.001},
+		{"USD", 0.01}, // нет в таблице — погрешность по умолчанию
+		{"", 0.01},
+	}
+	for _, tt := range tests {
+		if got := fc.Tolerance(tt.currency); got != tt.want {
+			t.Errorf("Tolerance(%q) = %g, ожидалось %g", tt.currency, got, tt.want)
+		}
+	}
+}
+
+// financeOrder — заказ, суммы которого сходятся
+func financeOrder(currency string) general.Order {
+	return general.Order{
+		OrderUID: "fin",
+		Payment: general.Payment{
+			Currency: currency, Amount: 1817.1, GoodsTotal: 317.1, DeliveryCost: 1500,
+		},
+		Items: []general.Item{
+			{Price: 453, Sale: 30, TotalPrice: 317.1},
+		},
+	}
+}
+
+func checks(mismatches []FinanceMismatch) []string {
+	var out []string
+	for _, m := range mismatches {
+		out = append(out, m.Check+" "+m.Path)
+	}
+	return out
+}
+
+func TestFinanceCheck(t *testing.T) {
+	fc := NewFinanceCheck(0.01, map[string]float64{"JPY": 1, "KWD": 0.001})
+
+	tests := []struct {
+		name   string
+		modify func(*general.Order)
+		want   []string
+	}{
+		{"суммы сходятся", func(*general.Order) {}, nil},
+		{"amount без custom_fee", func(o *general.Order) {
+			o.Payment.CustomFee = 5
+		}, []string{"amount payment.amount"}},
+		{"goods_total не равен сумме товаров", func(o *general.Order) {
+			o.Items = append(o.Items, general.Item{Price: 10, TotalPrice: 10})
+		}, []string{"goods_total payment.goods_total"}},
+		{"скидка посчитана неверно", func(o *general.Order) {
+			o.Items[0].TotalPrice = 453
+			o.Payment.GoodsTotal = 453
+			o.Payment.Amount = 1953
+		}, []string{"item_total items[0].total_price"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := financeOrder("USD")
+			tt.modify(&order)
+			if got := checks(fc.Check(order)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("расхождения %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinanceCheckToleranceByCurrency(t *testing.T) {
+	fc := NewFinanceCheck(0.01, map[string]float64{"JPY": 1, "KWD": 0.001})
+	order := financeOrder("")
+	order.Items[0].TotalPrice = 317.1
+	order.Payment.GoodsTotal = 317.1
+	order.Payment.Amount = 1817.6 // на полпроцента больше суммы
+
+	for currency, mismatched := range map[string]bool{"jpy": false, "USD": true, "KWD": true} {
+		order.Payment.Currency = currency
+		got := fc.Check(order)
+		if (len(got) > 0) != mismatched {
+			t.Fatalf("%s: расхождения %v, ожидалось расхождение: %v", currency, got, mismatched)
+		}
+		if mismatched && (got[0].Tolerance != fc.Tolerance(currency) || got[0].Expected != 1817.1 || got[0].Actual != 1817.6) {
+			t.Fatalf("%s: расхождение %+v", currency, got[0])
+		}
+	}
+
+	// Расхождение меньше цента видно только при допуске в тысячные
+	order.Payment.Amount = 1817.105
+	order.Payment.Currency = "KWD"
+	if got := fc.Check(order); len(got) != 1 {
+		t.Fatalf("KWD: расхождение в полцента не найдено: %v", got)
+	}
+	order.Payment.Currency = "USD"
+	if got := fc.Check(order); len(got) != 0 {
+		t.Fatalf("USD: расхождение в полцента превышает допуск: %v", got)
+	}
+}
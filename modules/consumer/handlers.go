@@ -0,0 +1,166 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"project_wb_l0/modules/general"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Kind — вид записи. По нему бд выбирает, как записать запись
+type Kind string
+
+const (
+	KindOrder  Kind = "order"  // заказ целиком (order-info)
+	KindStatus Kind = "status" // смена статуса заказа (order-status)
+	KindCancel Kind = "cancel" // отмена заказа (order-cancel)
+)
+
+// Handler разбирает и проверяет сообщение своего топика и возвращает запись для бд.
+// Ошибка, обёрнутая в ErrDecode, относится к этапу decode, остальные — к validate.
+// ErrRegistryUnavailable останавливает обработку пачки без коммита.
+// Координаты сообщения в записи заполняет консюмер
+type Handler interface {
+	Handle(ctx context.Context, msg kafka.Message) (Record, error)
+}
+
+// HandlerFunc — функция, реализующая Handler
+type HandlerFunc func(ctx context.Context, msg kafka.Message) (Record, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, msg kafka.Message) (Record, error) {
+	return f(ctx, msg)
+}
+
+// WithTopic подписывает консюмер ещё на один топик со своим обработчиком.
+// Топик читается в той же группе и теми же обработчиками партиций,
+// коммитится и останавливается вместе с остальными
+func WithTopic(topic string, h Handler) Option {
+	return func(c *Consumer) {
+		if _, ok := c.handlers[topic]; !ok {
+			c.topics = append(c.topics, topic)
+		}
+		c.handlers[topic] = h
+	}
+}
+
+// handleOrder — обработчик заказов: формат, схема, правила и сверка сумм
+func (c *Consumer) handleOrder(ctx context.Context, msg kafka.Message) (Record, error) {
+	validatedData := c.validateOrder(ctx, msg)
+	if validatedData.Err != nil {
+		return Record{}, validatedData.Err
+	}
+	rec := Record{Kind: KindOrder, Order: validatedData.Order}
+	if c.finance != nil {
+		rec.Mismatches = c.finance.Check(rec.Order)
+		for _, m := range rec.Mismatches {
+			log.Printf("Расхождение сумм в заказе %s: %s\n", rec.Order.OrderUID, m)
+		}
+	}
+	return rec, nil
+}
+
+// StatusHandler — обработчик смен статусов заказов (JSON general.OrderStatus).
+// Статус cancelled через него не принимается: отмена приходит своим топиком
+func StatusHandler() Handler {
+	return HandlerFunc(func(_ context.Context, msg kafka.Message) (Record, error) {
+		var st general.OrderStatus
+		if err := decodeEvent(msg, &st); err != nil {
+			return Record{}, err
+		}
+		var violations []Violation
+		violations = require(violations, "order_uid", st.OrderUID != "")
+		violations = require(violations, "status", st.Status != "")
+		violations = require(violations, "updated_at", !st.UpdatedAt.IsZero())
+		if st.Status == general.StatusCancelled {
+			violations = append(violations, Violation{
+				Path:     "status",
+				Rule:     "allowed",
+				Severity: SeverityReject,
+				Message:  "отмена заказа передаётся через топик отмен",
+			})
+		}
+		if len(violations) > 0 {
+			return Record{}, &ValidationError{Violations: violations}
+		}
+		return Record{Kind: KindStatus, Status: st}, nil
+	})
+}
+
+// CancelHandler — обработчик отмен заказов (JSON general.OrderCancel)
+func CancelHandler() Handler {
+	return HandlerFunc(func(_ context.Context, msg kafka.Message) (Record, error) {
+		var cancel general.OrderCancel
+		if err := decodeEvent(msg, &cancel); err != nil {
+			return Record{}, err
+		}
+		var violations []Violation
+		violations = require(violations, "order_uid", cancel.OrderUID != "")
+		violations = require(violations, "cancelled_at", !cancel.CancelledAt.IsZero())
+		if len(violations) > 0 {
+			return Record{}, &ValidationError{Violations: violations}
+		}
+		return Record{Kind: KindCancel, Cancel: cancel}, nil
+	})
+}
+
+// decodeEvent разбирает JSON-событие заказа. Другие форматы для событий не поддерживаются
+func decodeEvent(msg kafka.Message, v any) error {
+	if ct, ok := contentTypeOf(msg); ok && ct != ContentTypeJSON {
+		return fmt.Errorf("%w: формат %q не поддерживается в топике %s", ErrDecode, ct, msg.Topic)
+	}
+	if err := json.Unmarshal(msg.Value, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrDecode, err)
+	}
+	return nil
+}
+
+// require добавляет нарушение правила required, если поле не заполнено
+func require(violations []Violation, path string, ok bool) []Violation {
+	if ok {
+		return violations
+	}
+	return append(violations, Violation{
+		Path:     path,
+		Rule:     RuleRequired,
+		Severity: SeverityReject,
+		Message:  "поле не заполнено",
+	})
+}
+
+// topicCounters — счётчики сообщений одного топика
+type topicCounters struct {
+	received atomic.Int64
+	rejected atomic.Int64
+	written  atomic.Int64
+	failed   atomic.Int64
+}
+
+// TopicStats — статистика обработки сообщений топика
+type TopicStats struct {
+	Topic    string `json:"topic"`
+	Received int64  `json:"received"` // прочитано сообщений
+	Rejected int64  `json:"rejected"` // не прошли разбор или валидацию
+	Written  int64  `json:"written"`  // приняты бд
+	Failed   int64  `json:"failed"`   // не записались в бд
+}
+
+// Stats возвращает статистику обработки по каждому топику консюмера
+func (c *Consumer) Stats() []TopicStats {
+	out := make([]TopicStats, len(c.topics))
+	for i, topic := range c.topics {
+		s := c.stats[topic]
+		out[i] = TopicStats{
+			Topic:    topic,
+			Received: s.received.Load(),
+			Rejected: s.rejected.Load(),
+			Written:  s.written.Load(),
+			Failed:   s.failed.Load(),
+		}
+	}
+	return out
+}
@@ -0,0 +1,177 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/general"
+	"project_wb_l0/modules/retry"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// violationPaths возвращает пути нарушений из ошибки валидации
+func violationPaths(t *testing.T, err error) []string {
+	t.Helper()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("ожидалась ValidationError, получено %v", err)
+	}
+	var paths []string
+	for _, v := range verr.Violations {
+		paths = append(paths, v.Path)
+	}
+	return paths
+}
+
+func TestStatusHandler(t *testing.T) {
+	h := StatusHandler()
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	st := general.OrderStatus{OrderUID: "a", Status: "delivered", UpdatedAt: now}
+	rec, err := h.Handle(ctx, kafka.Message{Value: mustJSON(t, st)})
+	if err != nil || rec.Kind != KindStatus || rec.Status != st {
+		t.Fatalf("Handle = %+v, %v", rec, err)
+	}
+
+	_, err = h.Handle(ctx, kafka.Message{Value: []byte(`{"status":"delivered"}`)})
+	if got := violationPaths(t, err); fmt.Sprint(got) != "[order_uid updated_at]" {
+		t.Fatalf("нарушения %v", got)
+	}
+
+	// Отмена приходит только своим топиком
+	st.Status = general.StatusCancelled
+	_, err = h.Handle(ctx, kafka.Message{Value: mustJSON(t, st)})
+	if got := violationPaths(t, err); fmt.Sprint(got) != "[status]" {
+		t.Fatalf("нарушения %v", got)
+	}
+}
+
+func TestCancelHandler(t *testing.T) {
+	h := CancelHandler()
+	ctx := context.Background()
+
+	cancel := general.OrderCancel{OrderUID: "a", Reason: "передумал", CancelledAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	rec, err := h.Handle(ctx, kafka.Message{Value: mustJSON(t, cancel)})
+	if err != nil || rec.Kind != KindCancel || rec.Cancel != cancel {
+		t.Fatalf("Handle = %+v, %v", rec, err)
+	}
+
+	_, err = h.Handle(ctx, kafka.Message{Value: []byte(`{"reason":"передумал"}`)})
+	if got := violationPaths(t, err); fmt.Sprint(got) != "[order_uid cancelled_at]" {
+		t.Fatalf("нарушения %v", got)
+	}
+}
+
+func TestEventHandlersRejectUndecodable(t *testing.T) {
+	valid := []byte(`{"order_uid":"a","status":"new","updated_at":"2024-05-01T12:00:00Z"}`)
+	tests := []struct {
+		name string
+		msg  kafka.Message
+	}{
+		{"не JSON", kafka.Message{Value: []byte("{")}},
+		{"protobuf", withContentType(valid, ContentTypeProtobuf)},
+		{"avro", withContentType(valid, ContentTypeAvro)},
+	}
+	for _, h := range []Handler{StatusHandler(), CancelHandler()} {
+		for _, tt := range tests {
+			_, err := h.Handle(context.Background(), tt.msg)
+			if !errors.Is(err, ErrDecode) || stageOf(err) != StageDecode {
+				t.Fatalf("%s: ожидалась ErrDecode, получено %v", tt.name, err)
+			}
+		}
+	}
+	// Явно указанный JSON принимается
+	if _, err := StatusHandler().Handle(context.Background(), withContentType(valid, ContentTypeJSON)); err != nil {
+		t.Fatalf("JSON с заголовком: %v", err)
+	}
+}
+
+func TestProcessRoutesByTopic(t *testing.T) {
+	c := &Consumer{
+		topics:   []string{"order-info"},
+		handlers: make(map[string]Handler),
+		stats:    make(map[string]*topicCounters),
+		decoders: mustDecoders(t),
+		rules:    DefaultRules(),
+	}
+	c.handlers["order-info"] = HandlerFunc(c.handleOrder)
+	WithTopic("order-status", StatusHandler())(c)
+	WithTopic("order-cancel", CancelHandler())(c)
+	WithTopic("order-status", StatusHandler())(c) // повторная подписка заменяет обработчик
+	for _, topic := range c.topics {
+		c.stats[topic] = &topicCounters{}
+	}
+	if fmt.Sprint(c.topics) != "[order-info order-status order-cancel]" {
+		t.Fatalf("топики %v", c.topics)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	msgs := []kafka.Message{
+		{Topic: "order-status", Partition: 1, Offset: 10, Value: mustJSON(t, general.OrderStatus{OrderUID: "a", Status: "new", UpdatedAt: now})},
+		{Topic: "order-info", Partition: 0, Offset: 5, Value: mustJSON(t, sampleOrder())},
+		{Topic: "order-cancel", Partition: 2, Offset: 7, Value: mustJSON(t, general.OrderCancel{OrderUID: "a", CancelledAt: now})},
+		{Topic: "order-cancel", Partition: 2, Offset: 8, Value: []byte(`{}`)},
+	}
+
+	w := newWorker()
+	got := make(chan []Record, 1)
+	go func() {
+		batch := <-w.Send()
+		got <- batch
+		// Бд недоступна: без dead-letter топика пачка не коммитится, reader не нужен
+		w.RecieveAnswer() <- Answer{Err: retry.ErrExhausted}
+	}()
+	if err := c.process(context.Background(), w, msgs); !errors.Is(err, ErrHalted) {
+		t.Fatalf("process: %v", err)
+	}
+
+	batch := <-got
+	want := []struct {
+		kind      Kind
+		topic     string
+		partition int
+		offset    int64
+	}{
+		{KindStatus, "order-status", 1, 10},
+		{KindOrder, "order-info", 0, 5},
+		{KindCancel, "order-cancel", 2, 7},
+	}
+	if len(batch) != len(want) {
+		t.Fatalf("в бд ушло %d записей, ожидалось %d: %+v", len(batch), len(want), batch)
+	}
+	for i, w := range want {
+		rec := batch[i]
+		if rec.Kind != w.kind || rec.Topic != w.topic || rec.Partition != w.partition || rec.Offset != w.offset {
+			t.Fatalf("запись %d: %+v, ожидалось %+v", i, rec, w)
+		}
+	}
+	if batch[1].Order.OrderUID != sampleOrder().OrderUID || batch[0].Status.Status != "new" {
+		t.Fatalf("содержимое записей потеряно: %+v", batch)
+	}
+
+	stats := map[string]TopicStats{}
+	for _, s := range c.Stats() {
+		stats[s.Topic] = s
+	}
+	if s := stats["order-cancel"]; s.Received != 2 || s.Rejected != 1 {
+		t.Fatalf("статистика order-cancel %+v", s)
+	}
+	if s := stats["order-info"]; s.Received != 1 || s.Rejected != 0 {
+		t.Fatalf("статистика order-info %+v", s)
+	}
+}
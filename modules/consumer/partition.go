@@ -0,0 +1,198 @@
+package consumer
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// worker — обработчик части партиций. У каждого свои каналы для общения с бд,
+// поэтому бд пишет пачки разных обработчиков параллельно
+type worker struct {
+	sendDataBd chan []Record
+	answerBd   chan Answer
+	// pending — бд приняла пачку, но ответ не дождались: он ещё придёт в answerBd.
+	// Меняется только горутиной обработчика и run между запусками
+	pending bool
+}
+
+func newWorker() *worker {
+	return &worker{
+		sendDataBd: make(chan []Record),
+		answerBd:   make(chan Answer, 1), // буфер, чтобы бд не зависла на ответе упавшему чтению
+	}
+}
+
+// Канал для чтения пачек заказов
+func (w *worker) Send() <-chan []Record {
+	return w.sendDataBd
+}
+
+// Канал для принятия ответом
+func (w *worker) RecieveAnswer() chan<- Answer {
+	return w.answerBd
+}
+
+// write отправляет пачку в бд и ждёт ответа. ok = false, если ctx отменён раньше.
+// Если пачка уже ушла в бд, обработчик запоминает, что ответ ещё придёт (см. awaitPendingAnswer)
+func (w *worker) write(ctx context.Context, batch []Record) (answer Answer, ok bool) {
+	select {
+	case w.sendDataBd <- batch:
+	case <-ctx.Done():
+		return Answer{}, false
+	}
+	select {
+	case answer = <-w.answerBd:
+		return answer, true
+	case <-ctx.Done():
+		w.pending = true
+		return Answer{}, false
+	}
+}
+
+// awaitPendingAnswer дожидается ответа бд на пачку, брошенную упавшим чтением,
+// чтобы он не достался следующей пачке. Бд пишет брошенную пачку до конца,
+// поэтому новый запуск не начинается, пока она не записана
+func (w *worker) awaitPendingAnswer(ctx context.Context) {
+	if !w.pending {
+		return
+	}
+	log.Println("Ждём ответа бд на пачку упавшего чтения")
+	select {
+	case <-w.answerBd:
+		w.pending = false
+	case <-ctx.Done():
+	}
+}
+
+// serve читает сообщения и раздаёт их обработчикам, пока не отменён ctx.
+// Партиция всегда попадает к одному и тому же обработчику, поэтому порядок
+// внутри партиции сохраняется. Ошибки чтения считаются временными.
+// Остановка из-за бд и неудачный коммит в любом обработчике останавливают всех
+// и возвращаются супервизору
+func (c *Consumer) serve(ctx context.Context, rateLimit time.Duration) error {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Необязательное ограничение скорости — общее для всех обработчиков
+	var limit <-chan time.Time
+	if rateLimit > 0 {
+		clock := time.NewTicker(rateLimit)
+		defer clock.Stop()
+		limit = clock.C
+	}
+
+	queues := make([]chan kafka.Message, len(c.workers))
+	var wg sync.WaitGroup
+	for i, w := range c.workers {
+		queues[i] = make(chan kafka.Message, c.batchSize)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Паника в обработчике роняет весь запуск, как и в основной горутине
+			defer func() {
+				if r := recover(); r != nil {
+					cancel(panicError(r))
+				}
+			}()
+			if err := c.work(runCtx, w, queues[i], limit); err != nil {
+				cancel(err)
+			}
+		}()
+	}
+
+	c.dispatch(runCtx, queues)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return context.Cause(runCtx)
+}
+
+// dispatch читает сообщения из кафки и кладёт их в очередь обработчика их партиции (см. queueOf).
+// Если очередь полна, чтение ждёт — обратное давление доходит до кафки
+func (c *Consumer) dispatch(ctx context.Context, queues []chan kafka.Message) {
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Проблема с чтение данных из кафки: %v\n", err)
+			// Не крутимся вхолостую, пока кафка недоступна
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		select {
+		case queues[queueOf(msg, len(queues))] <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// queueOf выбирает обработчика для партиции топика. Смещение по хешу топика
+// раскладывает одинаковые номера партиций разных топиков по разным обработчикам
+func queueOf(msg kafka.Message, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(msg.Topic))
+	return int((h.Sum32() + uint32(msg.Partition)) % uint32(n))
+}
+
+// work собирает пачки из очереди обработчика и обрабатывает их по одной
+func (c *Consumer) work(ctx context.Context, w *worker, queue <-chan kafka.Message, limit <-chan time.Time) error {
+	for {
+		if limit != nil {
+			select {
+			case <-limit:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		msgs, ok := c.collect(ctx, queue)
+		if !ok {
+			return nil
+		}
+		if err := c.process(ctx, w, msgs); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// collect собирает пачку из очереди: ждёт первое сообщение сколько угодно,
+// а остальные — не дольше batchTimeout с момента его получения.
+// ok = false, если ctx отменён: собранные сообщения не коммитятся и будут прочитаны снова
+func (c *Consumer) collect(ctx context.Context, queue <-chan kafka.Message) (msgs []kafka.Message, ok bool) {
+	select {
+	case msg := <-queue:
+		msgs = append(msgs, msg)
+	case <-ctx.Done():
+		return nil, false
+	}
+
+	timer := time.NewTimer(c.batchTimeout)
+	defer timer.Stop()
+	for len(msgs) < c.batchSize {
+		select {
+		case msg := <-queue:
+			msgs = append(msgs, msg)
+		case <-timer.C:
+			// Время пачки вышло — отправляем то, что успели набрать
+			return msgs, true
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+	return msgs, true
+}
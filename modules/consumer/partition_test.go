@@ -0,0 +1,144 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Ответ бд на пачку, брошенную упавшим чтением, не должен достаться следующей пачке
+func TestWorkerLateAnswerGoesToAbandonedBatch(t *testing.T) {
+	w := newWorker()
+	stale := errors.New("ответ на старую пачку")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, ok := w.write(ctx, []Record{{Offset: 1}, {Offset: 2}}); ok {
+			t.Error("write после отмены вернул ok")
+		}
+	}()
+	<-w.Send() // бд приняла пачку
+	cancel()   // соседний обработчик уронил запуск
+	<-done
+
+	// Бд дописала пачку и ответила уже после того, как чтение упало
+	answered := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		w.RecieveAnswer() <- Answer{Err: stale, Errs: []error{stale, stale}}
+		close(answered)
+	}()
+	w.awaitPendingAnswer(context.Background())
+	<-answered
+
+	go func() {
+		<-w.Send()
+		w.RecieveAnswer() <- Answer{}
+	}()
+	answer, ok := w.write(context.Background(), []Record{{Offset: 3}})
+	if !ok {
+		t.Fatal("write не дождался ответа")
+	}
+	if err := answer.ErrFor(0); err != nil {
+		t.Fatalf("новая пачка получила ответ на старую: %v", err)
+	}
+}
+
+func TestAwaitPendingAnswerWithoutPendingBatch(t *testing.T) {
+	w := newWorker()
+	finished := make(chan struct{})
+	go func() {
+		w.awaitPendingAnswer(context.Background())
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("awaitPendingAnswer ждёт, хотя брошенной пачки нет")
+	}
+}
+
+func TestLastPerPartition(t *testing.T) {
+	msgs := []kafka.Message{
+		{Topic: "a", Partition: 0, Offset: 5},
+		{Topic: "a", Partition: 1, Offset: 3},
+		{Topic: "a", Partition: 0, Offset: 7},
+		{Topic: "b", Partition: 0, Offset: 1},
+		{Topic: "a", Partition: 0, Offset: 6}, // не по порядку
+		{Topic: "a", Partition: 1, Offset: 4},
+	}
+	got := lastPerPartition(msgs)
+	want := []string{"a/0@7", "a/1@4", "b/0@1"}
+	if len(got) != len(want) {
+		t.Fatalf("получено %v, ожидалось %v", got, want)
+	}
+	for i, msg := range got {
+		if s := fmt.Sprintf("%s/%d@%d", msg.Topic, msg.Partition, msg.Offset); s != want[i] {
+			t.Fatalf("получено %s, ожидалось %s", s, want[i])
+		}
+	}
+	if got := lastPerPartition(nil); len(got) != 0 {
+		t.Fatalf("пустая пачка: %v", got)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	c := &Consumer{batchSize: 3, batchTimeout: 50 * time.Millisecond}
+	fill := func(n int) chan kafka.Message {
+		queue := make(chan kafka.Message, 10)
+		for i := range n {
+			queue <- kafka.Message{Offset: int64(i)}
+		}
+		return queue
+	}
+
+	t.Run("по размеру", func(t *testing.T) {
+		queue := fill(5)
+		start := time.Now()
+		msgs, ok := c.collect(context.Background(), queue)
+		if !ok || len(msgs) != 3 || msgs[2].Offset != 2 {
+			t.Fatalf("collect = %v, %v; ожидалось 3 сообщения", msgs, ok)
+		}
+		if time.Since(start) >= c.batchTimeout {
+			t.Fatal("полная пачка ждала таймаута")
+		}
+		if len(queue) != 2 {
+			t.Fatalf("в очереди осталось %d сообщений, ожидалось 2", len(queue))
+		}
+	})
+
+	t.Run("по таймауту", func(t *testing.T) {
+		start := time.Now()
+		msgs, ok := c.collect(context.Background(), fill(2))
+		if !ok || len(msgs) != 2 {
+			t.Fatalf("collect = %v, %v; ожидалось 2 сообщения", msgs, ok)
+		}
+		if time.Since(start) < c.batchTimeout {
+			t.Fatal("неполная пачка отправлена раньше таймаута")
+		}
+	})
+
+	t.Run("отмена до первого сообщения", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		if msgs, ok := c.collect(ctx, fill(0)); ok || msgs != nil {
+			t.Fatalf("collect = %v, %v; ожидалась отмена", msgs, ok)
+		}
+	})
+
+	t.Run("отмена посреди пачки", func(t *testing.T) {
+		slow := &Consumer{batchSize: 3, batchTimeout: time.Hour}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		// Собранное не отдаётся: без коммита оно будет прочитано снова
+		if msgs, ok := slow.collect(ctx, fill(1)); ok || msgs != nil {
+			t.Fatalf("collect = %v, %v; ожидалась отмена", msgs, ok)
+		}
+	})
+}
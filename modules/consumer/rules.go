@@ -0,0 +1,331 @@
+package consumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"project_wb_l0/modules/general"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Типы правил валидации
+const (
+	RuleRequired = "required" // поле заполнено (для списков — не пустое)
+	RuleRegex    = "regex"    // строковое значение поля подходит под pattern
+	RuleRange    = "range"    // числовое значение поля в пределах [min, max]
+	RuleEquals   = "equals"   // значение поля совпадает со значением поля other
+)
+
+// Severity — что делать с заказом, нарушившим правило
+type Severity string
+
+const (
+	SeverityReject Severity = "reject" // заказ отклоняется
+	SeverityWarn   Severity = "warn"   // нарушение только пишется в лог
+)
+
+// Rule — одно правило валидации.
+// Field — путь по json-именам полей заказа через точку; "[]" после имени списка
+// означает «каждый элемент», например items[].price
+type Rule struct {
+	Field    string   `json:"field" yaml:"field"`
+	Type     string   `json:"type" yaml:"type"`
+	Pattern  string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Min      *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	Other    string   `json:"other,omitempty" yaml:"other,omitempty"`
+	Severity Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Message  string   `json:"message,omitempty" yaml:"message,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// RuleSet — набор правил валидации заказа
+type RuleSet struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Violation — нарушение правила в конкретном поле
+type Violation struct {
+	Path     string   `json:"path"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// ValidationError — все нарушения правил, найденные в заказе
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return "заказ не прошёл валидацию: " + strings.Join(parts, "; ")
+}
+
+// Rejected сообщает, есть ли среди нарушений отклоняющие заказ
+func (e *ValidationError) Rejected() bool {
+	for _, v := range e.Violations {
+		if v.Severity == SeverityReject {
+			return true
+		}
+	}
+	return false
+}
+
+// Warnings возвращает нарушения, которые только пишутся в лог
+func (e *ValidationError) Warnings() []Violation {
+	var out []Violation
+	for _, v := range e.Violations {
+		if v.Severity == SeverityWarn {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// DefaultRules — правила, которые проверялись до появления конфигурации правил
+func DefaultRules() *RuleSet {
+	rs := &RuleSet{Rules: []Rule{
+		{Field: "order_uid", Type: RuleRequired, Message: "поле ID не заполнено"},
+		{Field: "delivery.name", Type: RuleRequired, Message: "поле name  отнощения Delivery не заполнено"},
+		{Field: "payment.transaction", Type: RuleRequired, Message: "поле transaction отношения Payment не заполнено"},
+		{Field: "track_number", Type: RuleRequired, Message: "track_number в заказе не задан"},
+		{Field: "items[].track_number", Type: RuleEquals, Other: "track_number", Message: "неверный track_number"},
+	}}
+	if err := rs.compile(); err != nil {
+		panic(err)
+	}
+	return rs
+}
+
+// LoadRules читает правила из YAML (.yaml, .yml) или JSON (.json) файла
+func LoadRules(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения правил валидации: %w", err)
+	}
+	rs := &RuleSet{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, rs)
+	case ".json":
+		err = json.Unmarshal(data, rs)
+	default:
+		return nil, fmt.Errorf("неизвестный формат правил валидации %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора правил валидации %s: %w", path, err)
+	}
+	if err := rs.compile(); err != nil {
+		return nil, fmt.Errorf("ошибка в правилах валидации %s: %w", path, err)
+	}
+	return rs, nil
+}
+
+// compile проверяет правила по структуре Order и готовит регулярные выражения
+func (rs *RuleSet) compile() error {
+	orderType := reflect.TypeOf(general.Order{})
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Severity == "" {
+			r.Severity = SeverityReject
+		}
+		if r.Severity != SeverityReject && r.Severity != SeverityWarn {
+			return fmt.Errorf("правило %d (%s): неизвестная серьёзность %q", i, r.Field, r.Severity)
+		}
+		if _, err := fieldType(orderType, r.Field); err != nil {
+			return fmt.Errorf("правило %d: %w", i, err)
+		}
+		switch r.Type {
+		case RuleRequired:
+		case RuleRegex:
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return fmt.Errorf("правило %d (%s): %w", i, r.Field, err)
+			}
+			r.re = re
+		case RuleRange:
+			if r.Min == nil && r.Max == nil {
+				return fmt.Errorf("правило %d (%s): для range нужен min или max", i, r.Field)
+			}
+		case RuleEquals:
+			if strings.Contains(r.Other, "[]") {
+				return fmt.Errorf("правило %d (%s): other должно указывать на одно поле", i, r.Field)
+			}
+			if _, err := fieldType(orderType, r.Other); err != nil {
+				return fmt.Errorf("правило %d: %w", i, err)
+			}
+		default:
+			return fmt.Errorf("правило %d (%s): неизвестный тип %q", i, r.Field, r.Type)
+		}
+	}
+	return nil
+}
+
+// Check проверяет заказ всеми правилами и возвращает все найденные нарушения
+// (nil — нарушений нет)
+func (rs *RuleSet) Check(order general.Order) *ValidationError {
+	root := reflect.ValueOf(order)
+	var violations []Violation
+	for _, r := range rs.Rules {
+		for _, f := range resolve(root, r.Field) {
+			if msg, ok := r.check(root, f.value); !ok {
+				if r.Message != "" {
+					msg = r.Message
+				}
+				violations = append(violations, Violation{
+					Path:     f.path,
+					Rule:     r.Type,
+					Severity: r.Severity,
+					Message:  msg,
+				})
+			}
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// check проверяет одно значение. Возвращает сообщение о нарушении по умолчанию
+func (r *Rule) check(root, v reflect.Value) (string, bool) {
+	switch r.Type {
+	case RuleRequired:
+		if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			return "поле не заполнено", false
+		}
+	case RuleRegex:
+		if s := fmt.Sprint(v.Interface()); !r.re.MatchString(s) {
+			return fmt.Sprintf("значение %q не подходит под %s", s, r.Pattern), false
+		}
+	case RuleRange:
+		n, ok := number(v)
+		if !ok {
+			return "значение не число", false
+		}
+		if (r.Min != nil && n < *r.Min) || (r.Max != nil && n > *r.Max) {
+			return fmt.Sprintf("значение %v вне диапазона [%s, %s]", n, bound(r.Min), bound(r.Max)), false
+		}
+	case RuleEquals:
+		others := resolve(root, r.Other)
+		if len(others) != 1 {
+			return "нет поля " + r.Other, false
+		}
+		if !reflect.DeepEqual(v.Interface(), others[0].value.Interface()) {
+			return fmt.Sprintf("%v вместо %v (%s)", v.Interface(), others[0].value.Interface(), r.Other), false
+		}
+	}
+	return "", true
+}
+
+// field — найденное значение поля вместе с конкретным путём (items[2].price)
+type field struct {
+	path  string
+	value reflect.Value
+}
+
+// resolve находит все значения по пути правила
+func resolve(v reflect.Value, path string) []field {
+	fields := []field{{value: v}}
+	for _, seg := range strings.Split(path, ".") {
+		name, each := strings.CutSuffix(seg, "[]")
+		var next []field
+		for _, f := range fields {
+			sf, ok := structField(f.value.Type(), name)
+			if !ok {
+				continue
+			}
+			fv := f.value.FieldByIndex(sf.Index)
+			fp := joinPath(f.path, name)
+			if !each {
+				next = append(next, field{path: fp, value: fv})
+				continue
+			}
+			for i := 0; i < fv.Len(); i++ {
+				next = append(next, field{path: fp + "[" + strconv.Itoa(i) + "]", value: fv.Index(i)})
+			}
+		}
+		fields = next
+	}
+	return fields
+}
+
+// fieldType проверяет, что путь существует в типе t, и возвращает тип поля
+func fieldType(t reflect.Type, path string) (reflect.Type, error) {
+	if path == "" {
+		return nil, fmt.Errorf("не задано поле")
+	}
+	for _, seg := range strings.Split(path, ".") {
+		name, each := strings.CutSuffix(seg, "[]")
+		sf, ok := structField(t, name)
+		if !ok {
+			return nil, fmt.Errorf("в заказе нет поля %s", path)
+		}
+		t = sf.Type
+		if each {
+			if t.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("поле %s не список", path)
+			}
+			t = t.Elem()
+		}
+	}
+	return t, nil
+}
+
+// structField ищет поле структуры по имени из json-тега
+func structField(t reflect.Type, name string) (reflect.StructField, bool) {
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if tag == name {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func bound(b *float64) string {
+	if b == nil {
+		return "…"
+	}
+	return strconv.FormatFloat(*b, 'f', -1, 64)
+}
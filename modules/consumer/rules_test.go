@@ -0,0 +1,209 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"project_wb_l0/modules/general"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// writeRules сохраняет правила во временный файл с расширением ext
+func writeRules(t *testing.T, ext, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules"+ext)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadShippedRules(t *testing.T) {
+	rs, err := LoadRules("../../validation_rules.yaml")
+	if err != nil {
+		t.Fatalf("LoadRules: %v", err)
+	}
+	if len(rs.Rules) == 0 {
+		t.Fatal("правила не загружены")
+	}
+	if verr := rs.Check(sampleOrder()); verr != nil && verr.Rejected() {
+		t.Fatalf("пример заказа отклонён: %v", verr)
+	}
+}
+
+func TestLoadRulesYAMLAndJSON(t *testing.T) {
+	yamlPath := writeRules(t, ".yml", `
+rules:
+  - field: items[].price
+    type: range
+    min: 0
+    severity: warn
+  - field: order_uid
+    type: regex
+    pattern: '^[a-z]+$'
+`)
+	jsonPath := writeRules(t, ".json", `{"rules": [
+		{"field": "items[].price", "type": "range", "min": 0, "severity": "warn"},
+		{"field": "order_uid", "type": "regex", "pattern": "^[a-z]+$"}
+	]}`)
+
+	fromYAML, err := LoadRules(yamlPath)
+	if err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+	fromJSON, err := LoadRules(jsonPath)
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	for _, rs := range []*RuleSet{fromYAML, fromJSON} {
+		if len(rs.Rules) != 2 || rs.Rules[0].Severity != SeverityWarn || *rs.Rules[0].Min != 0 {
+			t.Fatalf("правило range разобрано неверно: %+v", rs.Rules[0])
+		}
+		// Серьёзность по умолчанию — reject, регулярное выражение скомпилировано
+		if r := rs.Rules[1]; r.Severity != SeverityReject || r.re == nil {
+			t.Fatalf("правило regex разобрано неверно: %+v", r)
+		}
+	}
+
+	if _, err := LoadRules(writeRules(t, ".toml", "")); err == nil {
+		t.Fatal("неизвестный формат принят")
+	}
+	if _, err := LoadRules(writeRules(t, ".json", "{")); err == nil {
+		t.Fatal("битый JSON принят")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	zero := 0.0
+	tests := []struct {
+		name string
+		rule Rule
+		want string
+	}{
+		{"неизвестное поле", Rule{Field: "delivery.nope", Type: RuleRequired}, "нет поля"},
+		{"пустое поле", Rule{Type: RuleRequired}, "не задано поле"},
+		{"[] у не списка", Rule{Field: "delivery[].name", Type: RuleRequired}, "не список"},
+		{"плохое выражение", Rule{Field: "order_uid", Type: RuleRegex, Pattern: "(["}, "error parsing regexp"},
+		{"range без границ", Rule{Field: "payment.amount", Type: RuleRange}, "нужен min или max"},
+		{"other со списком", Rule{Field: "track_number", Type: RuleEquals, Other: "items[].track_number"}, "одно поле"},
+		{"неизвестное other", Rule{Field: "track_number", Type: RuleEquals, Other: "nope"}, "нет поля"},
+		{"неизвестный тип", Rule{Field: "order_uid", Type: "unique", Min: &zero}, "неизвестный тип"},
+		{"неизвестная серьёзность", Rule{Field: "order_uid", Type: RuleRequired, Severity: "fatal"}, "неизвестная серьёзность"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &RuleSet{Rules: []Rule{tt.rule}}
+			err := rs.compile()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("compile: %v, ожидалась ошибка с %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func mustRules(t *testing.T, rules ...Rule) *RuleSet {
+	t.Helper()
+	rs := &RuleSet{Rules: rules}
+	if err := rs.compile(); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return rs
+}
+
+func TestCheckExpandsItemPaths(t *testing.T) {
+	zero := 0.0
+	rs := mustRules(t,
+		Rule{Field: "items[].price", Type: RuleRange, Min: &zero},
+		Rule{Field: "items[].track_number", Type: RuleEquals, Other: "track_number"},
+	)
+	order := sampleOrder()
+	item := order.Items[0]
+	bad := item
+	bad.Price = -1
+	bad.TrackNumber = "OTHER"
+	order.Items = []general.Item{item, item, bad}
+
+	verr := rs.Check(order)
+	if verr == nil {
+		t.Fatal("нарушения не найдены")
+	}
+	var paths []string
+	for _, v := range verr.Violations {
+		paths = append(paths, v.Path)
+	}
+	if want := []string{"items[2].price", "items[2].track_number"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("пути нарушений %v, ожидалось %v", paths, want)
+	}
+}
+
+func TestCheckCollectsAllViolations(t *testing.T) {
+	maxAmount := 10000.0
+	rs := mustRules(t,
+		Rule{Field: "order_uid", Type: RuleRequired},
+		Rule{Field: "delivery.phone", Type: RuleRegex, Pattern: `^\+\d+$`, Message: "неверный телефон"},
+		Rule{Field: "items", Type: RuleRequired},
+		Rule{Field: "payment.amount", Type: RuleRange, Max: &maxAmount},
+	)
+	order := sampleOrder()
+	order.OrderUID = ""
+	order.Delivery.Phone = "12-34"
+	order.Items = []general.Item{}
+	order.Payment.Amount = 20000
+
+	verr := rs.Check(order)
+	if verr == nil || len(verr.Violations) != 4 {
+		t.Fatalf("ожидалось 4 нарушения, получено %v", verr)
+	}
+	if v := verr.Violations[1]; v.Path != "delivery.phone" || v.Rule != RuleRegex || v.Message != "неверный телефон" {
+		t.Fatalf("нарушение regex %+v", v)
+	}
+	if !strings.Contains(verr.Error(), "order_uid") || !strings.Contains(verr.Error(), "payment.amount") {
+		t.Fatalf("в ошибке перечислены не все нарушения: %v", verr)
+	}
+	if rs.Check(sampleOrder()) != nil {
+		t.Fatal("нарушения у корректного заказа")
+	}
+}
+
+func TestWarnDoesNotReject(t *testing.T) {
+	rs := mustRules(t,
+		Rule{Field: "delivery.email", Type: RuleRequired, Severity: SeverityWarn},
+		Rule{Field: "order_uid", Type: RuleRegex, Pattern: "^[a-z]+$"},
+	)
+	order := sampleOrder()
+	order.Delivery.Email = ""
+
+	c := &Consumer{decoders: mustDecoders(t), rules: rs}
+	validate := func(order general.Order) error {
+		body, err := json.Marshal(order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.validateOrder(context.Background(), kafka.Message{Value: body}).Err
+	}
+
+	// Только предупреждение: заказ принимается
+	order.OrderUID = "abc"
+	if err := validate(order); err != nil {
+		t.Fatalf("предупреждение отклонило заказ: %v", err)
+	}
+	verr := rs.Check(order)
+	if verr == nil || verr.Rejected() || len(verr.Warnings()) != 1 {
+		t.Fatalf("ожидалось одно предупреждение: %v", verr)
+	}
+
+	// Предупреждение вместе с отклоняющим нарушением: заказ отклоняется
+	order.OrderUID = "ABC1"
+	if err := validate(order); err == nil {
+		t.Fatal("заказ с отклоняющим нарушением принят")
+	}
+	if verr := rs.Check(order); !verr.Rejected() || len(verr.Warnings()) != 1 {
+		t.Fatalf("ожидались предупреждение и отказ: %v", verr)
+	}
+}
@@ -0,0 +1,140 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"project_wb_l0/modules/retry"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Состояния консюмера для проверки здоровья
+const (
+	StateStarting   = "starting"   // первый запуск ещё не начался
+	StateRunning    = "running"    // читает сообщения
+	StateRestarting = "restarting" // упал и ждёт перезапуска
+	StateStopped    = "stopped"    // остановлен вместе с сервисом
+)
+
+// restartPolicy — задержка перед перезапуском упавшего чтения
+var restartPolicy = retry.Policy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// healthyRun — если чтение проработало дольше, счётчик задержки сбрасывается
+const healthyRun = time.Minute
+
+// Health — состояние консюмера
+type Health struct {
+	Topics      []string  `json:"topics"`
+	State       string    `json:"state"`
+	Since       time.Time `json:"since"`
+	Restarts    int       `json:"restarts"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+}
+
+// healthState — потокобезопасное хранилище Health
+type healthState struct {
+	mu sync.Mutex
+	h  Health
+}
+
+func (s *healthState) set(state string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.h.State != state {
+		s.h.State = state
+		s.h.Since = time.Now()
+	}
+	if state == StateRestarting {
+		s.h.Restarts++
+	}
+	if err != nil {
+		s.h.LastError = err.Error()
+		s.h.LastErrorAt = time.Now()
+	}
+}
+
+func (s *healthState) get() Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.h
+}
+
+// Health возвращает текущее состояние консюмера
+func (c *Consumer) Health() Health {
+	return c.health.get()
+}
+
+// supervise запускает чтение и перезапускает его с растущей задержкой,
+// если оно упало (паника, неудачный коммит, остановка из-за бд).
+// Каждый запуск открывает новый reader, поэтому незакоммиченные сообщения
+// будут прочитаны снова. Каналы закрываются только при отмене ctx
+func (c *Consumer) supervise(ctx context.Context, rateLimit time.Duration) {
+	defer func() {
+		for _, w := range c.workers {
+			close(w.sendDataBd)
+		}
+		if c.deadLetter != nil {
+			if err := c.deadLetter.Close(); err != nil {
+				log.Printf("Ошибка закрытия dead-letter writer: %v\n", err)
+			}
+		}
+		c.health.set(StateStopped, nil)
+	}()
+
+	attempt := 0
+	for {
+		started := time.Now()
+		c.health.set(StateRunning, nil)
+		err := c.run(ctx, rateLimit)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > healthyRun {
+			attempt = 0
+		}
+		delay := restartPolicy.Delay(attempt)
+		attempt++
+
+		c.health.set(StateRestarting, err)
+		log.Printf("Чтение топиков %s упало: %v. Перезапуск через %v\n", strings.Join(c.topics, ", "), err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run открывает reader и читает, пока не отменён ctx или не случилась фатальная ошибка.
+// Паника превращается в ошибку, чтобы супервизор мог перезапустить чтение
+func (c *Consumer) run(ctx context.Context, rateLimit time.Duration) (err error) {
+	for _, w := range c.workers {
+		w.awaitPendingAnswer(ctx)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	c.reader = kafka.NewReader(c.config)
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError(r)
+		}
+		if cerr := c.reader.Close(); cerr != nil {
+			log.Printf("Ошибка закрытия reader кафки: %v\n", cerr)
+		}
+	}()
+
+	return c.serveFn(ctx, rateLimit)
+}
+
+// panicError превращает пойманную панику в ошибку со стеком
+func panicError(r any) error {
+	return fmt.Errorf("паника: %v\n%s", r, debug.Stack())
+}
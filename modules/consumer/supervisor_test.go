@@ -0,0 +1,124 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/retry"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// closedBroker возвращает адрес, на котором никто не слушает
+func closedBroker(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// testConsumer создаёт консюмер без запуска супервизора, чтение подменяется serve
+func testConsumer(t *testing.T, serve func(ctx context.Context, rateLimit time.Duration) error) *Consumer {
+	t.Helper()
+	c := &Consumer{
+		config:  kafka.ReaderConfig{Brokers: []string{closedBroker(t)}, GroupID: "test", GroupTopics: []string{"order-info"}},
+		topics:  []string{"order-info"},
+		workers: []*worker{newWorker()},
+		serveFn: serve,
+	}
+	c.health.h = Health{Topics: c.topics, State: StateStarting, Since: time.Now()}
+	return c
+}
+
+func TestRunTurnsPanicIntoError(t *testing.T) {
+	c := testConsumer(t, func(context.Context, time.Duration) error {
+		panic("обработчик упал")
+	})
+	err := c.run(context.Background(), 0)
+	if err == nil || !strings.Contains(err.Error(), "паника: обработчик упал") {
+		t.Fatalf("run = %v, ожидалась ошибка с паникой", err)
+	}
+}
+
+func TestSuperviseRestartsFailedRuns(t *testing.T) {
+	defer func(p retry.Policy) { restartPolicy = p }(restartPolicy)
+	restartPolicy = retry.Policy{BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var runs atomic.Int32
+	running := make(chan struct{})
+	c := testConsumer(t, func(ctx context.Context, _ time.Duration) error {
+		switch runs.Add(1) {
+		case 1:
+			return errors.New("коммит не прошёл")
+		case 2:
+			panic("обработчик упал")
+		}
+		close(running)
+		<-ctx.Done()
+		return nil
+	})
+	if h := c.Health(); h.State != StateStarting {
+		t.Fatalf("до запуска состояние %q", h.State)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		c.supervise(ctx, 0)
+		close(stopped)
+	}()
+
+	select {
+	case <-running:
+	case <-time.After(5 * time.Second):
+		t.Fatal("чтение не перезапущено")
+	}
+	h := c.Health()
+	if h.State != StateRunning || h.Restarts != 2 {
+		t.Fatalf("после двух падений: %+v", h)
+	}
+	if !strings.Contains(h.LastError, "паника: обработчик упал") || h.LastErrorAt.IsZero() {
+		t.Fatalf("последняя ошибка %q", h.LastError)
+	}
+
+	cancel()
+	<-stopped
+	if h := c.Health(); h.State != StateStopped || h.Restarts != 2 {
+		t.Fatalf("после остановки: %+v", h)
+	}
+	if _, ok := <-c.workers[0].Send(); ok {
+		t.Fatal("канал пачек не закрыт после остановки")
+	}
+}
+
+func TestHealthStateTransitions(t *testing.T) {
+	var s healthState
+	s.set(StateRunning, nil)
+	since := s.get().Since
+
+	s.set(StateRunning, nil) // то же состояние не сбрасывает время
+	if h := s.get(); h.Since != since || h.Restarts != 0 {
+		t.Fatalf("повтор состояния изменил %+v", h)
+	}
+	s.set(StateRestarting, errors.New("упало"))
+	s.set(StateRunning, nil)
+	s.set(StateRestarting, errors.New("снова упало"))
+	h := s.get()
+	if h.State != StateRestarting || h.Restarts != 2 || h.LastError != "снова упало" {
+		t.Fatalf("после двух перезапусков: %+v", h)
+	}
+	s.set(StateRunning, nil) // успешный запуск не стирает последнюю ошибку
+	if h := s.get(); h.LastError != "снова упало" {
+		t.Fatalf("последняя ошибка стёрта: %+v", h)
+	}
+}
@@ -1,6 +1,12 @@
 package general
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrOrderNotFound — заказа с таким UID нет в БД
+var ErrOrderNotFound = errors.New("заказ не найден")
 
 type Order struct {
 	OrderUID          string    `json:"order_uid"`
@@ -60,3 +66,20 @@ type ValidateResult struct {
 	Order Order
 	Err   error
 }
+
+// StatusCancelled — статус отменённого заказа. Ставится только отменой из order-cancel
+const StatusCancelled = "cancelled"
+
+// OrderStatus — смена статуса заказа (топик order-status)
+type OrderStatus struct {
+	OrderUID  string    `json:"order_uid"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrderCancel — отмена заказа (топик order-cancel)
+type OrderCancel struct {
+	OrderUID    string    `json:"order_uid"`
+	Reason      string    `json:"reason"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
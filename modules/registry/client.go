@@ -0,0 +1,104 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// Client — клиент реестра схем. Схемы неизменяемы, поэтому однажды полученная
+// по номеру схема кэшируется навсегда
+type Client struct {
+	baseURL string
+	http    *http.Client
+
+	mu    sync.RWMutex
+	cache map[int]Schema
+}
+
+// NewClient создаёт клиента реестра по адресу baseURL (например, http://localhost:8081)
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: timeout},
+		cache:   make(map[int]Schema),
+	}
+}
+
+// SchemaByID возвращает схему по номеру
+func (c *Client) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	c.mu.RLock()
+	s, ok := c.cache[id]
+	c.mu.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &s); err != nil {
+		return Schema{}, fmt.Errorf("ошибка получения схемы %d: %w", id, err)
+	}
+	c.mu.Lock()
+	c.cache[id] = s
+	c.mu.Unlock()
+	return s, nil
+}
+
+// Register регистрирует схему в subject и возвращает её номер.
+// Повторная регистрация той же схемы возвращает тот же номер
+func (c *Client) Register(ctx context.Context, subject string, s Schema) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", s, &resp)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка регистрации схемы в %s: %w", subject, err)
+	}
+	c.mu.Lock()
+	c.cache[resp.ID] = s
+	c.mu.Unlock()
+	return resp.ID, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &apiError{Code: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return apiErr
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
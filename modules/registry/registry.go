@@ -0,0 +1,75 @@
+// Package registry — клиент реестра схем, совместимого с Confluent Schema Registry,
+// и его упрощённая замена в памяти для тестов и локального запуска
+package registry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Типы схем реестра
+const (
+	TypeAvro     = "AVRO"
+	TypeProtobuf = "PROTOBUF"
+	TypeJSON     = "JSON"
+)
+
+// MagicByte — первый байт сообщения, закодированного со ссылкой на схему в реестре
+const MagicByte = 0
+
+// ErrNotFramed — сообщение не начинается с magic byte и номера схемы
+var ErrNotFramed = errors.New("сообщение без ссылки на схему в реестре")
+
+// Schema — схема из реестра
+type Schema struct {
+	Type   string `json:"schemaType,omitempty"` // пусто — AVRO, как в Confluent
+	Schema string `json:"schema"`
+}
+
+// SchemaType возвращает тип схемы с учётом значения по умолчанию
+func (s Schema) SchemaType() string {
+	if s.Type == "" {
+		return TypeAvro
+	}
+	return s.Type
+}
+
+// Frame добавляет к payload magic byte и номер схемы (формат Confluent)
+func Frame(id int, payload []byte) []byte {
+	out := make([]byte, 5, 5+len(payload))
+	out[0] = MagicByte
+	binary.BigEndian.PutUint32(out[1:], uint32(id))
+	return append(out, payload...)
+}
+
+// Unframe отделяет номер схемы от payload
+func Unframe(data []byte) (id int, payload []byte, err error) {
+	if len(data) < 5 || data[0] != MagicByte {
+		return 0, nil, ErrNotFramed
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
+
+// IsFramed сообщает, похоже ли сообщение на закодированное со ссылкой на реестр.
+// JSON никогда не начинается с нулевого байта, поэтому путаницы с ним нет
+func IsFramed(data []byte) bool {
+	return len(data) >= 5 && data[0] == MagicByte
+}
+
+// apiError — ошибка в формате реестра
+type apiError struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+// IsNotFound сообщает, что реестр ответил «нет такой схемы или subject'а».
+// В отличие от недоступности реестра, повтор запроса тут не поможет
+func IsNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && (apiErr.Code == 404 || apiErr.Code/100 == 404)
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("реестр схем: %s (код %d)", e.Message, e.Code)
+}
@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T) (*Server, *Client) {
+	t.Helper()
+	srv, err := NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv, NewClient(srv.URL(), time.Second)
+}
+
+func TestRegisterAndFetch(t *testing.T) {
+	_, c := startServer(t)
+	ctx := context.Background()
+
+	avro := Schema{Schema: `"string"`}
+	proto := Schema{Type: TypeProtobuf, Schema: `syntax = "proto3"; message A {}`}
+	avroID, err := c.Register(ctx, "a-value", avro)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	protoID, err := c.Register(ctx, "b-value", proto)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if avroID == protoID {
+		t.Fatalf("разные схемы получили один номер %d", avroID)
+	}
+
+	// Та же схема в другом subject'е получает тот же номер
+	again, err := c.Register(ctx, "c-value", avro)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if again != avroID {
+		t.Fatalf("повторная регистрация: номер %d, ожидался %d", again, avroID)
+	}
+
+	// Новый клиент без кэша идёт за схемой на сервер
+	fresh := NewClient(c.baseURL, time.Second)
+	got, err := fresh.SchemaByID(ctx, protoID)
+	if err != nil {
+		t.Fatalf("SchemaByID: %v", err)
+	}
+	if got.SchemaType() != TypeProtobuf || got.Schema != proto.Schema {
+		t.Fatalf("SchemaByID = %+v, ожидалось %+v", got, proto)
+	}
+	got, err = fresh.SchemaByID(ctx, avroID)
+	if err != nil {
+		t.Fatalf("SchemaByID: %v", err)
+	}
+	if got.SchemaType() != TypeAvro {
+		t.Fatalf("тип схемы без schemaType = %q, ожидался %q", got.SchemaType(), TypeAvro)
+	}
+}
+
+func TestSchemaByIDNotFound(t *testing.T) {
+	_, c := startServer(t)
+	_, err := c.SchemaByID(context.Background(), 42)
+	if !IsNotFound(err) {
+		t.Fatalf("ожидалась ошибка «нет схемы», получено %v", err)
+	}
+}
+
+func TestSchemaByIDUnavailable(t *testing.T) {
+	srv, c := startServer(t)
+	srv.Close()
+	_, err := c.SchemaByID(context.Background(), 1)
+	if err == nil || IsNotFound(err) {
+		t.Fatalf("ожидалась ошибка соединения, получено %v", err)
+	}
+}
+
+func TestClientCachesSchemas(t *testing.T) {
+	srv, c := startServer(t)
+	ctx := context.Background()
+	id, err := c.Register(ctx, "a-value", Schema{Schema: `"long"`})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := c.SchemaByID(ctx, id); err != nil {
+		t.Fatalf("SchemaByID: %v", err)
+	}
+	// Схемы неизменяемы: после остановки реестра схема берётся из кэша
+	srv.Close()
+	if _, err := c.SchemaByID(ctx, id); err != nil {
+		t.Fatalf("SchemaByID из кэша: %v", err)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	payload := []byte{1, 2, 3}
+	framed := Frame(258, payload)
+	if !IsFramed(framed) {
+		t.Fatal("IsFramed = false для сообщения из Frame")
+	}
+	id, got, err := Unframe(framed)
+	if err != nil || id != 258 || !bytes.Equal(got, payload) {
+		t.Fatalf("Unframe = %d, %v, %v", id, got, err)
+	}
+	if IsFramed([]byte(`{"order_uid":"x"}`)) {
+		t.Fatal("JSON принят за сообщение со ссылкой на реестр")
+	}
+	if _, _, err := Unframe([]byte{0, 1}); !errors.Is(err, ErrNotFramed) {
+		t.Fatalf("Unframe короткого сообщения: %v", err)
+	}
+}
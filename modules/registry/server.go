@@ -0,0 +1,142 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Server — реестр схем в памяти с подмножеством API Confluent Schema Registry:
+// POST /subjects/{subject}/versions, GET /schemas/ids/{id}, GET /subjects,
+// GET /subjects/{subject}/versions/latest.
+// Предназначен для тестов и локального запуска без настоящего реестра
+type Server struct {
+	listener net.Listener
+	srv      *http.Server
+
+	mu       sync.Mutex
+	schemas  []Schema         // номер схемы — индекс + 1
+	ids      map[Schema]int   // одинаковые схемы получают один номер
+	subjects map[string][]int // версии subject'а — номера схем по порядку
+}
+
+// NewServer запускает реестр на addr (например, "127.0.0.1:0" — случайный порт)
+func NewServer(addr string) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: l,
+		ids:      make(map[Schema]int),
+		subjects: make(map[string][]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /subjects/{subject}/versions", s.register)
+	mux.HandleFunc("GET /schemas/ids/{id}", s.schemaByID)
+	mux.HandleFunc("GET /subjects", s.listSubjects)
+	mux.HandleFunc("GET /subjects/{subject}/versions/latest", s.latest)
+	s.srv = &http.Server{Handler: mux}
+
+	go func() {
+		if err := s.srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("registry: ошибка сервера: %v", err)
+		}
+	}()
+	return s, nil
+}
+
+// URL возвращает адрес реестра для NewClient
+func (s *Server) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Close останавливает реестр
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	var schema Schema
+	if err := json.NewDecoder(r.Body).Decode(&schema); err != nil || schema.Schema == "" {
+		writeError(w, http.StatusUnprocessableEntity, 42201, "Invalid schema")
+		return
+	}
+	if schema.Type == TypeAvro {
+		schema.Type = ""
+	}
+	subject := r.PathValue("subject")
+
+	s.mu.Lock()
+	id, ok := s.ids[schema]
+	if !ok {
+		s.schemas = append(s.schemas, schema)
+		id = len(s.schemas)
+		s.ids[schema] = id
+	}
+	versions := s.subjects[subject]
+	if len(versions) == 0 || versions[len(versions)-1] != id {
+		s.subjects[subject] = append(versions, id)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]int{"id": id})
+}
+
+func (s *Server) schemaByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil || id < 1 || id > len(s.schemas) {
+		writeError(w, http.StatusNotFound, 40403, "Schema not found")
+		return
+	}
+	writeJSON(w, s.schemas[id-1])
+}
+
+func (s *Server) listSubjects(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	subjects := make([]string, 0, len(s.subjects))
+	for name := range s.subjects {
+		subjects = append(subjects, name)
+	}
+	s.mu.Unlock()
+	sort.Strings(subjects)
+	writeJSON(w, subjects)
+}
+
+func (s *Server) latest(w http.ResponseWriter, r *http.Request) {
+	subject := r.PathValue("subject")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.subjects[subject]
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, 40401, "Subject not found")
+		return
+	}
+	id := versions[len(versions)-1]
+	schema := s.schemas[id-1]
+	writeJSON(w, map[string]any{
+		"subject":    subject,
+		"version":    len(versions),
+		"id":         id,
+		"schema":     schema.Schema,
+		"schemaType": schema.SchemaType(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", contentType)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, code int, msg string) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Code: code, Message: msg})
+}
@@ -0,0 +1,136 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrClosed — клиент уже закрыт
+var ErrClosed = errors.New("resp: клиент закрыт")
+
+// Client — клиент Redis с пулом соединений. Безопасен для конкурентного использования
+type Client struct {
+	addr     string
+	password string
+	timeout  time.Duration
+	idle     chan *conn
+	closed   chan struct{}
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// NewClient создаёт клиента для addr. Соединения открываются лениво,
+// в пуле хранится не больше poolSize простаивающих соединений.
+// timeout ограничивает подключение и каждую команду
+func NewClient(addr, password string, poolSize int, timeout time.Duration) *Client {
+	return &Client{
+		addr:     addr,
+		password: password,
+		timeout:  timeout,
+		idle:     make(chan *conn, max(1, poolSize)),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Do выполняет команду и возвращает ответ.
+// Ответ с типом Error возвращается как ServerError
+func (c *Client) Do(ctx context.Context, args ...string) (Value, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return Value{}, err
+	}
+	v, err := c.roundTrip(ctx, cn, args)
+	if err != nil {
+		// Состояние соединения неизвестно — не возвращаем его в пул
+		cn.Close()
+		return Value{}, err
+	}
+	c.put(cn)
+	if v.Type == Error {
+		return v, ServerError(v.Str)
+	}
+	return v, nil
+}
+
+// Close закрывает все простаивающие соединения
+func (c *Client) Close() error {
+	select {
+	case <-c.closed:
+		return nil
+	default:
+		close(c.closed)
+	}
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) roundTrip(ctx context.Context, cn *conn, args []string) (Value, error) {
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := cn.SetDeadline(deadline); err != nil {
+		return Value{}, err
+	}
+	if err := WriteCommand(cn.w, args...); err != nil {
+		return Value{}, err
+	}
+	return ReadValue(cn.r)
+}
+
+// get берёт соединение из пула или открывает новое
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case <-c.closed:
+		return nil, ErrClosed
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	d := net.Dialer{Timeout: c.timeout}
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if c.password != "" {
+		v, err := c.roundTrip(ctx, cn, []string{"AUTH", c.password})
+		if err == nil && v.Type == Error {
+			err = ServerError(v.Str)
+		}
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// put возвращает соединение в пул или закрывает его, если пул полон
+func (c *Client) put(cn *conn) {
+	select {
+	case <-c.closed:
+		cn.Close()
+		return
+	default:
+	}
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
@@ -0,0 +1,181 @@
+// Package resp реализует протокол Redis (RESP2): кодирование команд, чтение ответов
+// и небольшой сервер в памяти, который можно поднять прямо в процессе вместо Redis.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Типы значений RESP
+const (
+	SimpleString = '+'
+	Error        = '-'
+	Integer      = ':'
+	BulkString   = '$'
+	Array        = '*'
+)
+
+// ErrProtocol — собеседник прислал данные не по протоколу RESP
+var ErrProtocol = errors.New("нарушение протокола RESP")
+
+// Ограничения на длины из заголовков собеседника, как proto-max-bulk-len в Redis:
+// без них одна строка вида "$9223372036854775807" заставила бы выделить память без предела
+const (
+	MaxBulkLen  = 512 << 20 // байт в bulk-строке
+	MaxArrayLen = 1 << 20   // элементов в массиве
+	maxDepth    = 32        // вложенность массивов
+)
+
+// Value — одно значение RESP
+type Value struct {
+	Type  byte
+	Str   string  // SimpleString, Error, BulkString
+	Int   int64   // Integer
+	Array []Value // Array
+	Null  bool    // нулевая строка ($-1) или массив (*-1)
+}
+
+// ServerError — ответ сервера с типом Error
+type ServerError string
+
+func (e ServerError) Error() string {
+	return "redis: " + string(e)
+}
+
+// WriteCommand записывает команду как массив bulk-строк
+func WriteCommand(w *bufio.Writer, args ...string) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	return w.Flush()
+}
+
+// WriteValue записывает значение v (без Flush)
+func WriteValue(w *bufio.Writer, v Value) error {
+	var err error
+	switch v.Type {
+	case SimpleString, Error:
+		_, err = fmt.Fprintf(w, "%c%s\r\n", v.Type, v.Str)
+	case Integer:
+		_, err = fmt.Fprintf(w, ":%d\r\n", v.Int)
+	case BulkString:
+		if v.Null {
+			_, err = w.WriteString("$-1\r\n")
+		} else {
+			_, err = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v.Str), v.Str)
+		}
+	case Array:
+		if v.Null {
+			_, err = w.WriteString("*-1\r\n")
+			break
+		}
+		if _, err = fmt.Fprintf(w, "*%d\r\n", len(v.Array)); err != nil {
+			return err
+		}
+		for _, item := range v.Array {
+			if err = WriteValue(w, item); err != nil {
+				return err
+			}
+		}
+	default:
+		err = fmt.Errorf("%w: неизвестный тип %q", ErrProtocol, v.Type)
+	}
+	return err
+}
+
+// ReadValue читает одно значение RESP
+func ReadValue(r *bufio.Reader) (Value, error) {
+	return readValue(r, 0)
+}
+
+func readValue(r *bufio.Reader, depth int) (Value, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return Value{}, err
+	}
+	if len(line) == 0 {
+		return Value{}, fmt.Errorf("%w: пустая строка", ErrProtocol)
+	}
+
+	v := Value{Type: line[0]}
+	body := line[1:]
+	switch v.Type {
+	case SimpleString, Error:
+		v.Str = body
+	case Integer:
+		if v.Int, err = strconv.ParseInt(body, 10, 64); err != nil {
+			return Value{}, fmt.Errorf("%w: %v", ErrProtocol, err)
+		}
+	case BulkString:
+		n, err := readLen(body, MaxBulkLen)
+		if err != nil {
+			return Value{}, err
+		}
+		if n < 0 {
+			v.Null = true
+			break
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return Value{}, err
+		}
+		if buf[n] != '\r' || buf[n+1] != '\n' {
+			return Value{}, fmt.Errorf("%w: bulk-строка без \\r\\n", ErrProtocol)
+		}
+		v.Str = string(buf[:n])
+	case Array:
+		if depth >= maxDepth {
+			return Value{}, fmt.Errorf("%w: вложенность массивов больше %d", ErrProtocol, maxDepth)
+		}
+		n, err := readLen(body, MaxArrayLen)
+		if err != nil {
+			return Value{}, err
+		}
+		if n < 0 {
+			v.Null = true
+			break
+		}
+		// Память под элементы растёт по мере чтения, а не по заявленной длине
+		v.Array = make([]Value, 0, min(n, 1024))
+		for range n {
+			elem, err := readValue(r, depth+1)
+			if err != nil {
+				return Value{}, err
+			}
+			v.Array = append(v.Array, elem)
+		}
+	default:
+		return Value{}, fmt.Errorf("%w: неизвестный тип %q", ErrProtocol, v.Type)
+	}
+	return v, nil
+}
+
+// readLen разбирает длину из заголовка bulk-строки или массива.
+// -1 означает null, остальные отрицательные значения и длины больше limit отклоняются
+func readLen(body string, limit int) (int, error) {
+	n, err := strconv.Atoi(body)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrProtocol, err)
+	}
+	if n < -1 || n > limit {
+		return 0, fmt.Errorf("%w: недопустимая длина %d", ErrProtocol, n)
+	}
+	return n, nil
+}
+
+// readLine читает строку до \r\n и возвращает её без разделителя
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("%w: строка без \\r\\n", ErrProtocol)
+	}
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,221 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValueRoundTrip(t *testing.T) {
+	values := []Value{
+		{Type: SimpleString, Str: "OK"},
+		{Type: Error, Str: "ERR плохо"},
+		{Type: Integer, Int: -42},
+		{Type: BulkString, Str: "строка\r\nс переводом"},
+		{Type: BulkString, Null: true},
+		{Type: Array, Array: []Value{
+			{Type: BulkString, Str: "GET"},
+			{Type: Integer, Int: 7},
+			{Type: Array, Array: []Value{{Type: SimpleString, Str: "вложенный"}}},
+		}},
+	}
+	for _, v := range values {
+		var buf bytes.Buffer
+		w := bufio.NewWriter(&buf)
+		if err := WriteValue(w, v); err != nil {
+			t.Fatalf("WriteValue(%+v): %v", v, err)
+		}
+		w.Flush()
+		got, err := ReadValue(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("ReadValue(%q): %v", buf.String(), err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Fatalf("прочитано %+v, ожидалось %+v", got, v)
+		}
+	}
+}
+
+func TestReadValueRejectsMalformed(t *testing.T) {
+	inputs := []string{
+		"", "\r\n", "?x\r\n", ":abc\r\n", "$5\r\nab\r\n", "*2\r\n:1\r\n", "+OK",
+		"$2\r\nabcd", "$-2\r\n", "*-5\r\n",
+		// Длины, при которых n+2 переполняется или память выделяется без предела
+		"$9223372036854775807\r\n",
+		"$" + strconv.Itoa(MaxBulkLen+1) + "\r\n",
+		"*9223372036854775807\r\n",
+		"*" + strconv.Itoa(MaxArrayLen+1) + "\r\n",
+		strings.Repeat("*1\r\n", maxDepth+1) + ":1\r\n",
+	}
+	for _, in := range inputs {
+		if _, err := ReadValue(bufio.NewReader(strings.NewReader(in))); err == nil {
+			t.Errorf("ReadValue(%.40q): ожидалась ошибка", in)
+		}
+	}
+}
+
+func TestServerSurvivesHugeLength(t *testing.T) {
+	srv, c := startServer(t, "")
+	conn, err := net.Dial("tcp", srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("*1\r\n$9223372036854775807\r\n"))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	// Сервер закрывает соединение с нарушителем и продолжает обслуживать остальных
+	if _, err := conn.Read(make([]byte, 16)); err == nil {
+		t.Fatal("соединение с нарушителем не закрыто")
+	}
+	if v := do(t, c, "PING"); v.Str != "PONG" {
+		t.Fatalf("PING = %+v", v)
+	}
+}
+
+// startServer запускает сервер на случайном порту и клиента к нему
+func startServer(t *testing.T, password string) (*Server, *Client) {
+	t.Helper()
+	srv, err := NewServer("127.0.0.1:0", password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(srv.Addr(), password, 2, time.Second)
+	t.Cleanup(func() {
+		client.Close()
+		srv.Close()
+	})
+	return srv, client
+}
+
+// do выполняет команду и падает на ошибке
+func do(t *testing.T, c *Client, args ...string) Value {
+	t.Helper()
+	v, err := c.Do(context.Background(), args...)
+	if err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return v
+}
+
+func TestServerCommands(t *testing.T) {
+	_, c := startServer(t, "")
+
+	if v := do(t, c, "PING"); v.Str != "PONG" {
+		t.Fatalf("PING = %+v", v)
+	}
+	if v := do(t, c, "GET", "k"); !v.Null {
+		t.Fatalf("GET отсутствующего ключа = %+v", v)
+	}
+	do(t, c, "SET", "k", "v1")
+	if v := do(t, c, "GET", "k"); v.Str != "v1" {
+		t.Fatalf("GET = %+v, ожидалось v1", v)
+	}
+
+	// NX не перезаписывает, XX не создаёт
+	if v := do(t, c, "SET", "k", "v2", "NX"); !v.Null {
+		t.Fatalf("SET NX существующего ключа = %+v", v)
+	}
+	if v := do(t, c, "SET", "other", "v", "XX"); !v.Null {
+		t.Fatalf("SET XX отсутствующего ключа = %+v", v)
+	}
+	do(t, c, "SET", "k", "v2", "XX")
+	if v := do(t, c, "GET", "k"); v.Str != "v2" {
+		t.Fatalf("GET после SET XX = %+v", v)
+	}
+
+	do(t, c, "SET", "k2", "v")
+	if v := do(t, c, "EXISTS", "k", "k2", "nope"); v.Int != 2 {
+		t.Fatalf("EXISTS = %+v, ожидалось 2", v)
+	}
+	if v := do(t, c, "DBSIZE"); v.Int != 2 {
+		t.Fatalf("DBSIZE = %+v, ожидалось 2", v)
+	}
+	if v := do(t, c, "DEL", "k", "nope"); v.Int != 1 {
+		t.Fatalf("DEL = %+v, ожидалось 1", v)
+	}
+	do(t, c, "FLUSHALL")
+	if v := do(t, c, "DBSIZE"); v.Int != 0 {
+		t.Fatalf("DBSIZE после FLUSHALL = %+v", v)
+	}
+}
+
+func TestServerExpiry(t *testing.T) {
+	_, c := startServer(t, "")
+	do(t, c, "SET", "k", "v", "PX", "20")
+	if v := do(t, c, "GET", "k"); v.Str != "v" {
+		t.Fatalf("GET до истечения = %+v", v)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if v := do(t, c, "GET", "k"); !v.Null {
+		t.Fatalf("GET после истечения = %+v", v)
+	}
+	for _, args := range [][]string{
+		{"SET", "k", "v", "PX", "0"},
+		{"SET", "k", "v", "EX"},
+		{"SET", "k", "v", "KEEPTTL"},
+	} {
+		if _, err := c.Do(context.Background(), args...); err == nil {
+			t.Errorf("%v: ожидалась ошибка", args)
+		}
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	_, c := startServer(t, "")
+	_, err := c.Do(context.Background(), "HGETALL", "k")
+	var serr ServerError
+	if !errors.As(err, &serr) || !strings.HasPrefix(string(serr), "ERR unknown command") {
+		t.Fatalf("ожидалась ServerError, получено %v", err)
+	}
+	// После ошибки сервера соединение остаётся рабочим
+	if v := do(t, c, "PING", "эхо"); v.Str != "эхо" {
+		t.Fatalf("PING = %+v", v)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	srv, c := startServer(t, "secret")
+	do(t, c, "SET", "k", "v")
+
+	wrong := NewClient(srv.Addr(), "wrong", 1, time.Second)
+	defer wrong.Close()
+	if _, err := wrong.Do(context.Background(), "GET", "k"); err == nil {
+		t.Fatal("неверный пароль принят")
+	}
+
+	anon := NewClient(srv.Addr(), "", 1, time.Second)
+	defer anon.Close()
+	_, err := anon.Do(context.Background(), "GET", "k")
+	var serr ServerError
+	if !errors.As(err, &serr) || !strings.HasPrefix(string(serr), "NOAUTH") {
+		t.Fatalf("без AUTH: %v, ожидалась NOAUTH", err)
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	_, c := startServer(t, "")
+	do(t, c, "PING")
+	c.Close()
+	if _, err := c.Do(context.Background(), "PING"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("ожидалась ErrClosed, получено %v", err)
+	}
+}
+
+func TestClientServerDown(t *testing.T) {
+	srv, c := startServer(t, "")
+	do(t, c, "PING")
+	srv.Close()
+	// Соединение из пула оборвано, новое не открывается
+	for range 2 {
+		if _, err := c.Do(context.Background(), "PING"); err == nil {
+			t.Fatal("ожидалась ошибка при остановленном сервере")
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server — минимальный Redis-совместимый сервер в памяти.
+// Поддерживает PING, AUTH, GET, SET (с EX/PX/NX/XX), DEL, EXISTS, FLUSHALL и DBSIZE.
+// Предназначен для тестов и локального запуска без настоящего Redis
+type Server struct {
+	listener net.Listener
+	password string
+
+	mu   sync.Mutex
+	data map[string]serverEntry
+
+	wg     sync.WaitGroup
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+type serverEntry struct {
+	value     string
+	expiresAt time.Time // нулевое значение — без срока жизни
+}
+
+// NewServer запускает сервер на addr (например, "127.0.0.1:0" — случайный порт).
+// Если password не пустой, клиенты должны выполнить AUTH
+func NewServer(addr, password string) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: l,
+		password: password,
+		data:     make(map[string]serverEntry),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr возвращает адрес, на котором слушает сервер
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close останавливает сервер и закрывает все соединения
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("resp: ошибка приёма соединения: %v", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	authed := s.password == ""
+	for {
+		cmd, err := ReadValue(r)
+		if err != nil {
+			return
+		}
+		var reply Value
+		args, ok := commandArgs(cmd)
+		switch {
+		case !ok:
+			reply = errorValue("ERR команда должна быть массивом bulk-строк")
+		case strings.ToUpper(args[0]) == "AUTH":
+			reply, authed = s.auth(args)
+		case !authed:
+			reply = errorValue("NOAUTH Authentication required.")
+		default:
+			reply = s.exec(args)
+		}
+		if err := WriteValue(w, reply); err != nil {
+			return
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) auth(args []string) (Value, bool) {
+	if len(args) != 2 {
+		return errorValue("ERR wrong number of arguments for 'auth' command"), false
+	}
+	if s.password == "" || args[1] != s.password {
+		return errorValue("WRONGPASS invalid password"), false
+	}
+	return okValue(), true
+}
+
+func (s *Server) exec(args []string) Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		if len(args) > 1 {
+			return Value{Type: BulkString, Str: args[1]}
+		}
+		return Value{Type: SimpleString, Str: "PONG"}
+	case "GET":
+		if len(args) != 2 {
+			return wrongArgs(args[0])
+		}
+		e, ok := s.get(args[1], now)
+		if !ok {
+			return Value{Type: BulkString, Null: true}
+		}
+		return Value{Type: BulkString, Str: e.value}
+	case "SET":
+		return s.set(args, now)
+	case "DEL", "EXISTS":
+		if len(args) < 2 {
+			return wrongArgs(args[0])
+		}
+		var n int64
+		for _, key := range args[1:] {
+			if _, ok := s.get(key, now); ok {
+				n++
+				if strings.ToUpper(args[0]) == "DEL" {
+					delete(s.data, key)
+				}
+			}
+		}
+		return Value{Type: Integer, Int: n}
+	case "FLUSHALL", "FLUSHDB":
+		clear(s.data)
+		return okValue()
+	case "DBSIZE":
+		return Value{Type: Integer, Int: int64(len(s.data))}
+	default:
+		return errorValue("ERR unknown command '" + args[0] + "'")
+	}
+}
+
+// set выполняет SET key value [EX seconds | PX milliseconds] [NX | XX]
+func (s *Server) set(args []string, now time.Time) Value {
+	if len(args) < 3 {
+		return wrongArgs(args[0])
+	}
+	e := serverEntry{value: args[2]}
+	var nx, xx bool
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return errorValue("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return errorValue("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+			e.expiresAt = now.Add(time.Duration(n) * unit)
+			i++
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		default:
+			return errorValue("ERR syntax error")
+		}
+	}
+	_, exists := s.get(args[1], now)
+	if (nx && exists) || (xx && !exists) {
+		return Value{Type: BulkString, Null: true}
+	}
+	s.data[args[1]] = e
+	return okValue()
+}
+
+// get возвращает живую запись, удаляя истёкшую. Вызывается под s.mu
+func (s *Server) get(key string, now time.Time) (serverEntry, bool) {
+	e, ok := s.data[key]
+	if !ok {
+		return serverEntry{}, false
+	}
+	if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+		delete(s.data, key)
+		return serverEntry{}, false
+	}
+	return e, true
+}
+
+// commandArgs превращает массив bulk-строк в аргументы команды
+func commandArgs(v Value) ([]string, bool) {
+	if v.Type != Array || len(v.Array) == 0 {
+		return nil, false
+	}
+	args := make([]string, len(v.Array))
+	for i, a := range v.Array {
+		if a.Type != BulkString || a.Null {
+			return nil, false
+		}
+		args[i] = a.Str
+	}
+	return args, true
+}
+
+func okValue() Value {
+	return Value{Type: SimpleString, Str: "OK"}
+}
+
+func errorValue(msg string) Value {
+	return Value{Type: Error, Str: msg}
+}
+
+func wrongArgs(cmd string) Value {
+	return errorValue("ERR wrong number of arguments for '" + strings.ToLower(cmd) + "' command")
+}
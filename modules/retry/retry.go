@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrExhausted — попытки закончились, а ошибка всё ещё временная
+var ErrExhausted = errors.New("попытки исчерпаны")
+
+// Policy — экспоненциальная задержка со случайным разбросом (full jitter):
+// перед n-й повторной попыткой ждём случайное время из [0, min(MaxDelay, BaseDelay*2^n)]
+type Policy struct {
+	Attempts  int // общее число попыток, включая первую
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Delay возвращает задержку перед повторной попыткой номер attempt (с нуля)
+func (p Policy) Delay(attempt int) time.Duration {
+	ceiling := p.MaxDelay
+	if attempt < 62 {
+		if d := p.BaseDelay << attempt; d > 0 && d < ceiling {
+			ceiling = d
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// Do выполняет fn, повторяя её, пока retryable считает ошибку временной.
+// Постоянная ошибка возвращается сразу. Если попытки кончились на временной ошибке,
+// она возвращается обёрнутой в ErrExhausted. Отмена ctx прерывает ожидание
+// и возвращает последнюю ошибку
+func Do(ctx context.Context, p Policy, retryable func(error) bool, fn func() error) error {
+	attempts := max(1, p.Attempts)
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(p.Delay(attempt - 1)):
+			case <-ctx.Done():
+				return err
+			}
+		}
+		err = fn()
+		if err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w (%d): %w", ErrExhausted, attempts, err)
+}
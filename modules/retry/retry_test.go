@@ -0,0 +1,96 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTemporary = errors.New("временная ошибка")
+
+func isTemporary(err error) bool { return errors.Is(err, errTemporary) }
+
+func TestDelayWithinCeiling(t *testing.T) {
+	p := Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{0, 10 * time.Millisecond},
+		{1, 20 * time.Millisecond},
+		{2, 40 * time.Millisecond},
+		{3, 50 * time.Millisecond},
+		{62, 50 * time.Millisecond}, // сдвиг переполнил бы int64
+		{1000, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for range 100 {
+			if d := p.Delay(tt.attempt); d < 0 || d > tt.ceiling {
+				t.Fatalf("Delay(%d) = %v, ожидалось в [0, %v]", tt.attempt, d, tt.ceiling)
+			}
+		}
+	}
+	if d := (Policy{}).Delay(3); d != 0 {
+		t.Fatalf("нулевая политика: Delay = %v", d)
+	}
+}
+
+func TestDoStopsOnSuccess(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{Attempts: 5}, isTemporary, func() error {
+		calls++
+		if calls < 3 {
+			return errTemporary
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("err = %v, попыток %d, ожидалось nil и 3", err, calls)
+	}
+}
+
+func TestDoReturnsPermanentErrorAtOnce(t *testing.T) {
+	permanent := errors.New("постоянная ошибка")
+	calls := 0
+	err := Do(context.Background(), Policy{Attempts: 5}, isTemporary, func() error {
+		calls++
+		return permanent
+	})
+	if err != permanent || calls != 1 {
+		t.Fatalf("err = %v, попыток %d, ожидалась постоянная ошибка после одной попытки", err, calls)
+	}
+}
+
+func TestDoWrapsExhausted(t *testing.T) {
+	for _, attempts := range []int{0, 1, 4} {
+		calls := 0
+		err := Do(context.Background(), Policy{Attempts: attempts}, isTemporary, func() error {
+			calls++
+			return errTemporary
+		})
+		if want := max(1, attempts); calls != want {
+			t.Fatalf("Attempts=%d: попыток %d, ожидалось %d", attempts, calls, want)
+		}
+		if !errors.Is(err, ErrExhausted) || !errors.Is(err, errTemporary) {
+			t.Fatalf("Attempts=%d: ошибка %v не обёрнута в ErrExhausted с исходной", attempts, err)
+		}
+	}
+}
+
+func TestDoCancelInterruptsWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	start := time.Now()
+	err := Do(ctx, Policy{Attempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}, isTemporary, func() error {
+		calls++
+		cancel()
+		return errTemporary
+	})
+	if time.Since(start) > time.Second {
+		t.Fatal("отмена не прервала ожидание")
+	}
+	if calls != 1 || err != errTemporary || errors.Is(err, ErrExhausted) {
+		t.Fatalf("err = %v, попыток %d, ожидалась последняя ошибка после одной попытки", err, calls)
+	}
+}
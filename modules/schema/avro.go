@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"project_wb_l0/modules/avro"
+	"project_wb_l0/modules/general"
+)
+
+//go:embed order.v1.avsc
+var orderAvsc string
+
+// OrderAvsc возвращает схему заказа в формате Avro
+func OrderAvsc() string {
+	return orderAvsc
+}
+
+// OrderAvro разбирает встроенную Avro-схему заказа
+func OrderAvro() (*avro.Schema, error) {
+	return avro.Parse(orderAvsc)
+}
+
+// AvroToJSON декодирует Avro-запись по схеме writer и переводит её в JSON.
+// Имена полей Avro совпадают с json-именами полей general.Order, поэтому
+// дальше сообщение проверяется и разбирается так же, как JSON из Kafka
+func AvroToJSON(writer *avro.Schema, data []byte) ([]byte, error) {
+	v, err := writer.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// EncodeOrderAvro кодирует заказ по Avro-схеме s
+func EncodeOrderAvro(s *avro.Schema, order general.Order) ([]byte, error) {
+	body, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	out, err := s.Encode(v)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка кодирования заказа в Avro: %w", err)
+	}
+	return out, nil
+}
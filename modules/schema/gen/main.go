@@ -0,0 +1,25 @@
+// Команда gen записывает JSON Schema заказа, сгенерированную из general.Order
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"project_wb_l0/modules/schema"
+)
+
+func main() {
+	out := flag.String("out", "order.v1.schema.json", "файл для схемы")
+	flag.Parse()
+
+	body, err := json.MarshalIndent(schema.GenerateOrder(), "", "  ")
+	if err != nil {
+		log.Fatalf("Ошибка генерации схемы: %v", err)
+	}
+	if err := os.WriteFile(*out, append(body, '\n'), 0o644); err != nil {
+		log.Fatalf("Ошибка записи схемы: %v", err)
+	}
+	log.Printf("Схема заказа записана в %s", *out)
+}
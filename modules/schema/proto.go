@@ -0,0 +1,307 @@
+package schema
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"time"
+
+	"project_wb_l0/modules/general"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+//go:embed order.v1.proto
+var orderProto string
+
+// OrderProto возвращает описание заказа в формате Protocol Buffers
+func OrderProto() string {
+	return orderProto
+}
+
+// setter разбирает значение одного поля сообщения
+type setter func(typ protowire.Type, v []byte) error
+
+// DecodeOrderProto разбирает заказ, закодированный по order.v1.proto.
+// Неизвестные поля пропускаются, как принято в protobuf
+func DecodeOrderProto(data []byte) (general.Order, error) {
+	var o general.Order
+	err := protoFields(data, map[protowire.Number]setter{
+		1:  protoString(&o.OrderUID),
+		2:  protoString(&o.TrackNumber),
+		3:  protoString(&o.Entry),
+		4:  protoMessage(func(b []byte) error { return decodeDelivery(b, &o.Delivery) }),
+		5:  protoMessage(func(b []byte) error { return decodePayment(b, &o.Payment) }),
+		6:  protoMessage(func(b []byte) error { return decodeItem(b, &o.Items) }),
+		7:  protoString(&o.Locale),
+		8:  protoString(&o.InternalSignature),
+		9:  protoString(&o.CustomerID),
+		10: protoString(&o.DeliveryService),
+		11: protoString(&o.Shardkey),
+		12: protoString(&o.SmID),
+		13: protoTimestamp(&o.DateCreated),
+		14: protoString(&o.OofShard),
+	})
+	if err != nil {
+		return general.Order{}, fmt.Errorf("неверный protobuf заказа: %w", err)
+	}
+	return o, nil
+}
+
+func decodeDelivery(b []byte, d *general.Delivery) error {
+	return protoFields(b, map[protowire.Number]setter{
+		1: protoString(&d.Name),
+		2: protoString(&d.Phone),
+		3: protoString(&d.Zip),
+		4: protoString(&d.City),
+		5: protoString(&d.Address),
+		6: protoString(&d.Region),
+		7: protoString(&d.Email),
+	})
+}
+
+func decodePayment(b []byte, p *general.Payment) error {
+	return protoFields(b, map[protowire.Number]setter{
+		1:  protoString(&p.Transaction),
+		2:  protoString(&p.RequestID),
+		3:  protoString(&p.Currency),
+		4:  protoString(&p.Provider),
+		5:  protoDouble(&p.Amount),
+		6:  protoTimestamp(&p.PaymentDT),
+		7:  protoString(&p.Bank),
+		8:  protoDouble(&p.DeliveryCost),
+		9:  protoDouble(&p.GoodsTotal),
+		10: protoDouble(&p.CustomFee),
+	})
+}
+
+func decodeItem(b []byte, items *[]general.Item) error {
+	var it general.Item
+	err := protoFields(b, map[protowire.Number]setter{
+		1:  protoString(&it.ChrtID),
+		2:  protoString(&it.TrackNumber),
+		3:  protoDouble(&it.Price),
+		4:  protoString(&it.Rid),
+		5:  protoString(&it.Name),
+		6:  protoInt(&it.Sale),
+		7:  protoString(&it.Size),
+		8:  protoDouble(&it.TotalPrice),
+		9:  protoString(&it.NmID),
+		10: protoString(&it.Brand),
+		11: protoInt(&it.Status),
+	})
+	*items = append(*items, it)
+	return err
+}
+
+// protoFields обходит поля сообщения и передаёт известные соответствующим setter'ам
+func protoFields(b []byte, fields map[protowire.Number]setter) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		m := protowire.ConsumeFieldValue(num, typ, b)
+		if m < 0 {
+			return protowire.ParseError(m)
+		}
+		if set, ok := fields[num]; ok {
+			if err := set(typ, b[:m]); err != nil {
+				return fmt.Errorf("поле %d: %w", num, err)
+			}
+		}
+		b = b[m:]
+	}
+	return nil
+}
+
+func wrongType(want, got protowire.Type) error {
+	return fmt.Errorf("ожидался тип %d, получен %d", want, got)
+}
+
+func protoString(p *string) setter {
+	return func(typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return wrongType(protowire.BytesType, typ)
+		}
+		b, _ := protowire.ConsumeBytes(v)
+		*p = string(b)
+		return nil
+	}
+}
+
+func protoDouble(p *float64) setter {
+	return func(typ protowire.Type, v []byte) error {
+		if typ != protowire.Fixed64Type {
+			return wrongType(protowire.Fixed64Type, typ)
+		}
+		bits, _ := protowire.ConsumeFixed64(v)
+		*p = math.Float64frombits(bits)
+		return nil
+	}
+}
+
+func protoInt(p *int) setter {
+	return func(typ protowire.Type, v []byte) error {
+		if typ != protowire.VarintType {
+			return wrongType(protowire.VarintType, typ)
+		}
+		n, _ := protowire.ConsumeVarint(v)
+		*p = int(int32(n))
+		return nil
+	}
+}
+
+func protoMessage(decode func([]byte) error) setter {
+	return func(typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return wrongType(protowire.BytesType, typ)
+		}
+		b, _ := protowire.ConsumeBytes(v)
+		return decode(b)
+	}
+}
+
+// protoTimestamp разбирает google.protobuf.Timestamp
+func protoTimestamp(p *time.Time) setter {
+	return protoMessage(func(b []byte) error {
+		var seconds, nanos int64
+		err := protoFields(b, map[protowire.Number]setter{
+			1: func(typ protowire.Type, v []byte) error {
+				if typ != protowire.VarintType {
+					return wrongType(protowire.VarintType, typ)
+				}
+				n, _ := protowire.ConsumeVarint(v)
+				seconds = int64(n)
+				return nil
+			},
+			2: func(typ protowire.Type, v []byte) error {
+				if typ != protowire.VarintType {
+					return wrongType(protowire.VarintType, typ)
+				}
+				n, _ := protowire.ConsumeVarint(v)
+				nanos = int64(int32(n))
+				return nil
+			},
+		})
+		*p = time.Unix(seconds, nanos).UTC()
+		return err
+	})
+}
+
+// EncodeOrderProto кодирует заказ по order.v1.proto.
+// Нулевые значения не записываются, как принято в proto3
+func EncodeOrderProto(o general.Order) []byte {
+	var b []byte
+	b = appendString(b, 1, o.OrderUID)
+	b = appendString(b, 2, o.TrackNumber)
+	b = appendString(b, 3, o.Entry)
+	b = appendMessage(b, 4, encodeDelivery(o.Delivery))
+	b = appendMessage(b, 5, encodePayment(o.Payment))
+	for _, it := range o.Items {
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodeItem(it))
+	}
+	b = appendString(b, 7, o.Locale)
+	b = appendString(b, 8, o.InternalSignature)
+	b = appendString(b, 9, o.CustomerID)
+	b = appendString(b, 10, o.DeliveryService)
+	b = appendString(b, 11, o.Shardkey)
+	b = appendString(b, 12, o.SmID)
+	b = appendTimestamp(b, 13, o.DateCreated)
+	b = appendString(b, 14, o.OofShard)
+	return b
+}
+
+func encodeDelivery(d general.Delivery) []byte {
+	var b []byte
+	b = appendString(b, 1, d.Name)
+	b = appendString(b, 2, d.Phone)
+	b = appendString(b, 3, d.Zip)
+	b = appendString(b, 4, d.City)
+	b = appendString(b, 5, d.Address)
+	b = appendString(b, 6, d.Region)
+	b = appendString(b, 7, d.Email)
+	return b
+}
+
+func encodePayment(p general.Payment) []byte {
+	var b []byte
+	b = appendString(b, 1, p.Transaction)
+	b = appendString(b, 2, p.RequestID)
+	b = appendString(b, 3, p.Currency)
+	b = appendString(b, 4, p.Provider)
+	b = appendDouble(b, 5, p.Amount)
+	b = appendTimestamp(b, 6, p.PaymentDT)
+	b = appendString(b, 7, p.Bank)
+	b = appendDouble(b, 8, p.DeliveryCost)
+	b = appendDouble(b, 9, p.GoodsTotal)
+	b = appendDouble(b, 10, p.CustomFee)
+	return b
+}
+
+func encodeItem(it general.Item) []byte {
+	var b []byte
+	b = appendString(b, 1, it.ChrtID)
+	b = appendString(b, 2, it.TrackNumber)
+	b = appendDouble(b, 3, it.Price)
+	b = appendString(b, 4, it.Rid)
+	b = appendString(b, 5, it.Name)
+	b = appendInt(b, 6, it.Sale)
+	b = appendString(b, 7, it.Size)
+	b = appendDouble(b, 8, it.TotalPrice)
+	b = appendString(b, 9, it.NmID)
+	b = appendString(b, 10, it.Brand)
+	b = appendInt(b, 11, it.Status)
+	return b
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendDouble(b []byte, num protowire.Number, f float64) []byte {
+	if f == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(f))
+}
+
+func appendInt(b []byte, num protowire.Number, n int) []byte {
+	if n == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(int64(n)))
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	if len(msg) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendTimestamp(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	var ts []byte
+	if s := t.Unix(); s != 0 {
+		ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(s))
+	}
+	if n := t.Nanosecond(); n != 0 {
+		ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(n))
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, ts)
+}
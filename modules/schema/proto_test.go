@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"project_wb_l0/modules/general"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func testOrder() general.Order {
+	return general.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Delivery: general.Delivery{
+			Name: "Test Testov", Phone: "+9720000000", Zip: "2639809",
+			City: "Kiryat Mozkin", Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com",
+		},
+		Payment: general.Payment{
+			Transaction: "b563feb7b2b84b6test", Currency: "USD", Provider: "wbpay",
+			Amount: 1817, PaymentDT: time.Unix(1637907727, 0).UTC(), Bank: "alpha",
+			DeliveryCost: 1500, GoodsTotal: 317,
+		},
+		Items: []general.Item{{
+			ChrtID: "9934930", TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "ab4219087a764ae0btest",
+			Name: "Mascaras", Sale: 30, Size: "0", TotalPrice: 317, NmID: "2389212", Brand: "Vivienne Sabo", Status: 202,
+		}},
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		Shardkey:        "9",
+		SmID:            "99",
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 123456789, time.UTC),
+		OofShard:        "1",
+	}
+}
+
+func TestOrderProtoRoundTrip(t *testing.T) {
+	in := testOrder()
+	out, err := DecodeOrderProto(EncodeOrderProto(in))
+	if err != nil {
+		t.Fatalf("DecodeOrderProto: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("после кодирования и декодирования\nполучено %+v\nожидалось %+v", out, in)
+	}
+}
+
+func TestDecodeOrderProtoSkipsUnknownFields(t *testing.T) {
+	data := EncodeOrderProto(testOrder())
+	data = protowire.AppendTag(data, 99, protowire.BytesType)
+	data = protowire.AppendString(data, "новое поле")
+	out, err := DecodeOrderProto(data)
+	if err != nil {
+		t.Fatalf("DecodeOrderProto: %v", err)
+	}
+	if out.OrderUID != testOrder().OrderUID {
+		t.Fatalf("order_uid = %q", out.OrderUID)
+	}
+}
+
+func TestDecodeOrderProtoRejectsMalformed(t *testing.T) {
+	valid := EncodeOrderProto(testOrder())
+	tests := map[string][]byte{
+		"обрезанное сообщение": valid[:len(valid)-1],
+		"неверный тип поля":    protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 1),
+		"длина больше данных":  protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.BytesType), 1<<62),
+		"незаконченный тег":    {0x80},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeOrderProto(data); err == nil {
+				t.Fatal("ожидалась ошибка")
+			}
+		})
+	}
+}
+
+// FuzzDecodeOrderProto проверяет, что произвольные данные не роняют декодер
+func FuzzDecodeOrderProto(f *testing.F) {
+	f.Add(EncodeOrderProto(testOrder()))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DecodeOrderProto(data)
+	})
+}
+
+func TestOrderAvroRoundTrip(t *testing.T) {
+	s, err := OrderAvro()
+	if err != nil {
+		t.Fatalf("OrderAvro: %v", err)
+	}
+	in := testOrder()
+	in.DateCreated = in.DateCreated.Truncate(time.Microsecond) // timestamp-micros
+	data, err := EncodeOrderAvro(s, in)
+	if err != nil {
+		t.Fatalf("EncodeOrderAvro: %v", err)
+	}
+	body, err := AvroToJSON(s, data)
+	if err != nil {
+		t.Fatalf("AvroToJSON: %v", err)
+	}
+	if err := mustOrder(t).Validate(body, true); err != nil {
+		t.Fatalf("JSON из Avro не проходит схему: %v", err)
+	}
+}
+
+func mustOrder(t *testing.T) *Schema {
+	t.Helper()
+	s, err := Order()
+	if err != nil {
+		t.Fatalf("Order: %v", err)
+	}
+	return s
+}
@@ -0,0 +1,163 @@
+// Package schema описывает JSON Schema заказа, сгенерированную из general.Order,
+// и проверяет по ней сообщения из Kafka.
+// Схема хранится в репозитории рядом с кодом; после изменения general.Order
+// её нужно перегенерировать: go generate ./modules/schema
+package schema
+
+//go:generate go run ./gen -out order.v1.schema.json
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"project_wb_l0/modules/general"
+)
+
+// OrderVersion — версия схемы заказа. Повышается при несовместимых изменениях general.Order
+const OrderVersion = "v1"
+
+// OrderID — идентификатор схемы заказа
+const OrderID = "urn:project_wb_l0:schema:order:" + OrderVersion
+
+//go:embed order.v1.schema.json
+var orderJSON []byte
+
+// OrderJSON возвращает схему заказа в том виде, в каком она лежит в репозитории
+func OrderJSON() []byte {
+	return orderJSON
+}
+
+// Order разбирает встроенную схему заказа
+func Order() (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(orderJSON, s); err != nil {
+		return nil, fmt.Errorf("повреждённая схема заказа: %w", err)
+	}
+	return s, nil
+}
+
+// Schema — подмножество JSON Schema (draft 2020-12), которого хватает для описания заказа
+type Schema struct {
+	Schema               string     `json:"$schema,omitempty"`
+	ID                   string     `json:"$id,omitempty"`
+	Title                string     `json:"title,omitempty"`
+	Type                 string     `json:"type"`
+	Format               string     `json:"format,omitempty"`
+	Properties           Properties `json:"properties,omitempty"`
+	Required             []string   `json:"required,omitempty"`
+	AdditionalProperties *bool      `json:"additionalProperties,omitempty"`
+	Items                *Schema    `json:"items,omitempty"`
+}
+
+// Property — свойство объекта
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Properties — свойства объекта в порядке полей структуры
+type Properties []Property
+
+func (p Properties) MarshalJSON() ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		name, err := json.Marshal(prop.Name)
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(prop.Schema)
+		if err != nil {
+			return nil, err
+		}
+		sb.Write(name)
+		sb.WriteByte(':')
+		sb.Write(body)
+	}
+	sb.WriteByte('}')
+	return []byte(sb.String()), nil
+}
+
+func (p *Properties) UnmarshalJSON(data []byte) error {
+	var raw map[string]*Schema
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	// Порядок свойств для проверки не важен
+	*p = (*p)[:0]
+	for name, s := range raw {
+		*p = append(*p, Property{Name: name, Schema: s})
+	}
+	return nil
+}
+
+// property ищет свойство по имени
+func (p Properties) property(name string) (*Schema, bool) {
+	for _, prop := range p {
+		if prop.Name == name {
+			return prop.Schema, true
+		}
+	}
+	return nil, false
+}
+
+// GenerateOrder строит схему заказа из general.Order
+func GenerateOrder() *Schema {
+	s := generate(reflect.TypeOf(general.Order{}))
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.ID = OrderID
+	s.Title = "Order"
+	return s
+}
+
+// generate строит схему для типа. Поля без omitempty в json-теге обязательны,
+// лишние поля в объектах запрещены
+func generate(t reflect.Type) *Schema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: generate(t.Elem())}
+	case reflect.Pointer:
+		return generate(t.Elem())
+	case reflect.Struct:
+		closed := false
+		s := &Schema{Type: "object", AdditionalProperties: &closed}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			s.Properties = append(s.Properties, Property{Name: name, Schema: generate(f.Type)})
+			if !strings.Contains(opts, "omitempty") {
+				s.Required = append(s.Required, name)
+			}
+		}
+		return s
+	}
+	panic(fmt.Sprintf("schema: неподдерживаемый тип %s", t))
+}
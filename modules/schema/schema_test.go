@@ -0,0 +1,130 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Встроенный файл схемы должен совпадать с тем, что генерируется из general.Order:
+// иначе после изменения структуры забыли выполнить go generate ./modules/schema
+func TestEmbeddedSchemaUpToDate(t *testing.T) {
+	body, err := json.MarshalIndent(GenerateOrder(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(append(body, '\n'), OrderJSON()) {
+		t.Fatal("order.v1.schema.json устарел, выполните go generate ./modules/schema")
+	}
+	if _, err := Order(); err != nil {
+		t.Fatalf("Order: %v", err)
+	}
+}
+
+// validOrder — заказ, подходящий под схему, в виде JSON-объекта
+func validOrder() map[string]any {
+	return map[string]any{
+		"order_uid": "b563feb7b2b84b6test", "track_number": "WBILMTESTTRACK", "entry": "WBIL",
+		"delivery": map[string]any{
+			"name": "Test Testov", "phone": "+9720000000", "zip": "2639809", "city": "Kiryat Mozkin",
+			"address": "Ploshad Mira 15", "region": "Kraiot", "email": "test@gmail.com",
+		},
+		"payment": map[string]any{
+			"transaction": "b563feb7b2b84b6test", "request_id": "", "currency": "USD", "provider": "wbpay",
+			"amount": 1817, "payment_dt": "2021-11-26T06:22:19Z", "bank": "alpha",
+			"delivery_cost": 1500, "goods_total": 317, "custom_fee": 0,
+		},
+		"items": []any{map[string]any{
+			"chrt_id": "9934930", "track_number": "WBILMTESTTRACK", "price": 453, "rid": "ab4219087a764ae0btest",
+			"name": "Mascaras", "sale": 30, "size": "0", "total_price": 317, "nm_id": "2389212",
+			"brand": "Vivienne Sabo", "status": 202,
+		}},
+		"locale": "en", "internal_signature": "", "customer_id": "test", "delivery_service": "meest",
+		"shardkey": "9", "sm_id": "99", "date_created": "2021-11-26T06:22:19.123456Z", "oof_shard": "1",
+	}
+}
+
+func mustOrderSchema(t *testing.T) *Schema {
+	t.Helper()
+	s, err := Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// problems проверяет документ и возвращает пути несоответствий
+func problems(t *testing.T, s *Schema, doc map[string]any, strict bool) []string {
+	t.Helper()
+	body, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Validate(body, strict)
+	if err == nil {
+		return nil
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("ожидалась ValidationError, получено %v", err)
+	}
+	var paths []string
+	for _, p := range verr.Problems {
+		paths = append(paths, p.Path)
+	}
+	return paths
+}
+
+func TestValidate(t *testing.T) {
+	s := mustOrderSchema(t)
+	tests := []struct {
+		name   string
+		modify func(doc map[string]any)
+		strict bool
+		want   []string
+	}{
+		{"корректный заказ", func(map[string]any) {}, true, nil},
+		{"неизвестное поле в строгом режиме", func(doc map[string]any) {
+			doc["extra"] = 1
+			doc["delivery"].(map[string]any)["floor"] = "3"
+		}, true, []string{"$.delivery.floor", "$.extra"}},
+		{"неизвестное поле без строгого режима", func(doc map[string]any) {
+			doc["extra"] = 1
+		}, false, nil},
+		{"неверные типы", func(doc map[string]any) {
+			doc["order_uid"] = 42
+			doc["payment"].(map[string]any)["amount"] = "1817"
+			doc["items"].([]any)[0].(map[string]any)["sale"] = 30.5
+		}, false, []string{"$.items[0].sale", "$.order_uid", "$.payment.amount"}},
+		{"нет обязательного поля", func(doc map[string]any) {
+			delete(doc, "track_number")
+			delete(doc["payment"].(map[string]any), "currency")
+		}, false, []string{"$.track_number", "$.payment.currency"}},
+		{"неверная дата", func(doc map[string]any) {
+			doc["date_created"] = "26.11.2021"
+		}, false, []string{"$.date_created"}},
+		{"items: null", func(doc map[string]any) {
+			doc["items"] = nil
+		}, false, []string{"$.items"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := validOrder()
+			tt.modify(doc)
+			got := problems(t, s, doc, tt.strict)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("несоответствия %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsInvalidJSON(t *testing.T) {
+	err := mustOrderSchema(t).Validate([]byte(`{"order_uid":`), false)
+	if err == nil || !strings.Contains(err.Error(), "неверный JSON") {
+		t.Fatalf("ожидалась ошибка разбора, получено %v", err)
+	}
+}
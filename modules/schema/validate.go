@@ -0,0 +1,145 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Problem — несоответствие документа схеме
+type Problem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError — все несоответствия документа схеме
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		parts[i] = p.Path + ": " + p.Message
+	}
+	return "сообщение не соответствует схеме: " + strings.Join(parts, "; ")
+}
+
+// Validate проверяет JSON-документ по схеме и возвращает все несоответствия.
+// В строгом режиме поля, которых нет в схеме, считаются ошибкой;
+// иначе они пропускаются, как при обычном json.Unmarshal
+func (s *Schema) Validate(data []byte, strict bool) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("неверный JSON: %w", err)
+	}
+	v := validator{strict: strict}
+	v.check(s, doc, "$")
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+type validator struct {
+	strict   bool
+	problems []Problem
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) check(s *Schema, doc any, path string) {
+	switch s.Type {
+	case "object":
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			v.fail(path, "ожидался объект, получено %s", typeName(doc))
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				v.fail(path+"."+name, "обязательное поле отсутствует")
+			}
+		}
+		// Обходим поля в порядке имён, чтобы ошибки шли в одном и том же порядке
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties.property(name)
+			if !ok {
+				if v.strict && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					v.fail(path+"."+name, "неизвестное поле")
+				}
+				continue
+			}
+			v.check(prop, obj[name], path+"."+name)
+		}
+	case "array":
+		arr, ok := doc.([]any)
+		if !ok {
+			v.fail(path, "ожидался массив, получено %s", typeName(doc))
+			return
+		}
+		if s.Items != nil {
+			for i, el := range arr {
+				v.check(s.Items, el, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case "string":
+		str, ok := doc.(string)
+		if !ok {
+			v.fail(path, "ожидалась строка, получено %s", typeName(doc))
+			return
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				v.fail(path, "ожидалась дата в формате RFC 3339, получено %q", str)
+			}
+		}
+	case "number":
+		if _, ok := doc.(json.Number); !ok {
+			v.fail(path, "ожидалось число, получено %s", typeName(doc))
+		}
+	case "integer":
+		n, ok := doc.(json.Number)
+		if !ok {
+			v.fail(path, "ожидалось целое число, получено %s", typeName(doc))
+			return
+		}
+		if _, err := n.Int64(); err != nil {
+			v.fail(path, "ожидалось целое число, получено %s", n)
+		}
+	case "boolean":
+		if _, ok := doc.(bool); !ok {
+			v.fail(path, "ожидалось true или false, получено %s", typeName(doc))
+		}
+	}
+}
+
+func typeName(doc any) string {
+	switch doc.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "объект"
+	case []any:
+		return "массив"
+	case string:
+		return "строка"
+	case json.Number:
+		return "число"
+	case bool:
+		return "логическое значение"
+	}
+	return fmt.Sprintf("%T", doc)
+}